@@ -2,22 +2,68 @@ package main
 
 import (
 	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/database/memory"
 	"WST_lab1_server_new1/internal/database/postgres"
+	"WST_lab1_server_new1/internal/database/sqlite"
+	"WST_lab1_server_new1/internal/logging"
 	"WST_lab1_server_new1/internal/transport"
 	"fmt"
+	"os"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	config.Init()
-	storage, err := postgres.Init()
+	logging.InitializeLogger()
+	//Подкоманды сервера
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		case "user":
+			if err := runUser(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		default:
+			fmt.Printf("unknown command %q\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+	storage, err := initStorage()
 	if err != nil {
 		fmt.Printf("Error initializing database: %v\n", err)
 		return
 	}
+	if err := bootstrapAdmin(storage.UserRepository); err != nil {
+		fmt.Printf("Error creating administrator: %v\n", err)
+		return
+	}
+	if err := warnLegacyDigestSecrets(storage.UserRepository); err != nil {
+		fmt.Printf("Error checking users: %v\n", err)
+		return
+	}
+
+	//Периодическая очистка удаленных записей старше срока хранения
+	if retention := config.GeneralServerSetting.TombstoneRetention; retention > 0 {
+		stop := database.StartPurge(storage.PersonRepository.WithActor(database.SystemActor), retention, config.GeneralServerSetting.PurgeInterval)
+		defer stop()
+	}
 
-	router := gin.Default()
+	//Логгер и восстановление после паники подключаются в transport.Init
+	router := gin.New()
+	//Без явного списка gin доверяет X-Forwarded-For от любого клиента
+	if err := router.SetTrustedProxies(config.GeneralServerSetting.TrustedProxies); err != nil {
+		fmt.Printf("Error setting trusted proxies: %v\n", err)
+		return
+	}
 
 	transport.Init(router, storage)
 
@@ -28,3 +74,16 @@ func main() {
 	}
 
 }
+
+// Инициализация хранилища по драйверу из файла конфигурации
+func initStorage() (*database.Storage, error) {
+	switch config.DatabaseSetting.Driver {
+	case "", "postgres":
+		return postgres.Init()
+	case "sqlite":
+		return sqlite.Init()
+	case "memory":
+		return memory.Init()
+	}
+	return nil, fmt.Errorf("unknown database driver %q", config.DatabaseSetting.Driver)
+}
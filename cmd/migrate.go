@@ -0,0 +1,82 @@
+package main
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database/gormdb"
+	"WST_lab1_server_new1/internal/database/postgres"
+	"WST_lab1_server_new1/internal/database/sqlite"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = "usage: migrate up|down|status|to N"
+
+// Подключение к базе данных драйвера из конфигурации без миграций и заполнения
+func openDatabase() (*gorm.DB, error) {
+	switch config.DatabaseSetting.Driver {
+	case "", "postgres":
+		return postgres.Open()
+	case "sqlite":
+		return sqlite.Open()
+	case "memory":
+		return nil, errors.New("memory driver has no persistent database")
+	}
+	return nil, fmt.Errorf("unknown database driver %q", config.DatabaseSetting.Driver)
+}
+
+/*
+Команда migrate: up - применить все миграции, down - откатить последнюю,
+status - показать состояние, to N - привести схему к версии N
+*/
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	migrator, err := gormdb.Migrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.To(ctx, version)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+	}
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/auth"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/database/gormdb"
+	"WST_lab1_server_new1/internal/logging"
+	"WST_lab1_server_new1/internal/models"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+)
+
+const userUsage = "usage: user add USERNAME [ROLE] | user passwd|digest|disable|enable USERNAME | user role USERNAME ROLE | user list | user hash\n" +
+	"user digest enables WS-Security PasswordDigest for the user; the secret is encrypted with " + auth.DigestKeyEnv + " (32 bytes in base64)"
+
+// Чтение пароля из стандартного ввода (одна строка)
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+/*
+Команда user: add - создать пользователя (роль по умолчанию reader), passwd - сменить пароль,
+digest - задать секрет WS-Security PasswordDigest (пустой ввод удаляет секрет).
+PasswordDigest включается для пользователя только заданием секрета; секрет хранится
+зашифрованным ключом из WST_DIGEST_KEY, поэтому digest требует эту переменную окружения.
+Остальные команды:
+role - сменить роль, disable/enable - заблокировать/разблокировать, list - список пользователей,
+hash - вывести bcrypt хеш пароля для списка users в файле конфигурации.
+Пароль читается из стандартного ввода
+*/
+func runUser(args []string) error {
+	if len(args) == 0 {
+		return errors.New(userUsage)
+	}
+	if args[0] == "hash" {
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		fmt.Println(hash)
+		return nil
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	//Таблица пользователей создается миграцией
+	if err := gormdb.Migrate(db); err != nil {
+		return err
+	}
+	users := &gormdb.UserRepository{DB: db}
+	//Пользователи из файла конфигурации создаются так же, как при запуске сервера
+	if err := database.SeedUsers(users, config.GeneralServerSetting.Users); err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		list, err := users.ListUsers()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "USERNAME\tROLE\tSTATUS\tCREATED AT")
+		for _, user := range list {
+			status := "active"
+			if user.Disabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", user.Username, user.Role, status, user.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+	}
+
+	if len(args) < 2 || args[1] == "" {
+		return errors.New(userUsage)
+	}
+	username := args[1]
+	//Роль: необязательный аргумент add и обязательный аргумент role
+	role := models.RoleReader
+	switch {
+	case len(args) == 3 && (args[0] == "add" || args[0] == "role"):
+		role = args[2]
+		if !models.ValidRole(role) {
+			return fmt.Errorf("unknown role %q (reader, editor, admin)", role)
+		}
+	case len(args) != 2 || args[0] == "role":
+		return errors.New(userUsage)
+	}
+	switch args[0] {
+	case "add":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		if _, err := users.AddUser(&models.User{Username: username, PasswordHash: hash, Role: role}); err != nil {
+			return err
+		}
+		fmt.Printf("user %s created\n", username)
+		return nil
+	case "passwd", "digest", "role", "disable", "enable":
+		user, err := users.GetUser(username)
+		if err != nil {
+			return err
+		}
+		switch args[0] {
+		case "passwd":
+			password, err := readPassword()
+			if err != nil {
+				return err
+			}
+			if user.PasswordHash, err = auth.HashPassword(password); err != nil {
+				return err
+			}
+		case "digest":
+			//Секрет нужен в исходном виде для вычисления PasswordDigest,
+			//поэтому он не хешируется, а шифруется ключом сервера
+			secret, err := readPassword()
+			if err != nil {
+				return err
+			}
+			if user.DigestSecret, err = encryptDigestSecret(secret); err != nil {
+				return err
+			}
+		case "role":
+			user.Role = role
+		case "disable":
+			user.Disabled = true
+		case "enable":
+			user.Disabled = false
+		}
+		if err := users.UpdateUser(user); err != nil {
+			return err
+		}
+		fmt.Printf("user %s updated\n", username)
+		return nil
+	}
+	return errors.New(userUsage)
+}
+
+// Шифрование секрета PasswordDigest ключом из WST_DIGEST_KEY; пустой секрет запрещает PasswordDigest
+func encryptDigestSecret(secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	key, err := auth.DigestKeyFromEnv()
+	if err != nil {
+		return "", err
+	}
+	if key == nil {
+		return "", fmt.Errorf("%s is required to store a digest secret", auth.DigestKeyEnv)
+	}
+	return auth.EncryptDigestSecret(key, secret)
+}
+
+/*
+Предупреждение о секретах PasswordDigest, сохраненных в открытом виде до введения шифрования:
+такие секреты не принимаются, их нужно задать заново командой user digest
+*/
+func warnLegacyDigestSecrets(users database.UserRepository) error {
+	list, err := users.ListUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range list {
+		if auth.IsLegacyDigestSecret(user.DigestSecret) {
+			logging.Logger.Warn("Unencrypted digest secret is ignored: run \"user digest USERNAME\" to set it again", zap.String("username", user.Username))
+		}
+	}
+	return nil
+}
+
+// Переменные окружения для создания первого администратора
+const (
+	adminUsernameEnv = "WST_ADMIN_USERNAME"
+	adminPasswordEnv = "WST_ADMIN_PASSWORD"
+)
+
+/*
+Создание первого администратора при запуске, если в хранилище нет ни одного пользователя.
+Имя и пароль задает оператор переменными окружения WST_ADMIN_USERNAME (по умолчанию admin)
+и WST_ADMIN_PASSWORD; учетной записи по умолчанию нет. Без пароля сервер запускается,
+но операции, требующие аутентификации, недоступны до создания пользователя командой user add
+*/
+func bootstrapAdmin(users database.UserRepository) error {
+	list, err := users.ListUsers()
+	if err != nil {
+		return err
+	}
+	if len(list) > 0 {
+		return nil
+	}
+	password := os.Getenv(adminPasswordEnv)
+	if password == "" {
+		logging.Logger.Warn("No users configured: set " + adminPasswordEnv + " or run \"user add USERNAME admin\" to create an administrator")
+		return nil
+	}
+	username := os.Getenv(adminUsernameEnv)
+	if username == "" {
+		username = "admin"
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if _, err := users.AddUser(&models.User{Username: username, PasswordHash: hash, Role: models.RoleAdmin}); err != nil {
+		return err
+	}
+	logging.Logger.Info("Administrator created from environment", zap.String("username", username))
+	return nil
+}
@@ -16,13 +16,23 @@ type Config struct {
 	GeneralServer GeneralServerConfig `yaml:"generalServer"`
 	HTTPServer    HTTPServerConfig    `yaml:"httpServer"`
 	Database      DatabaseConfig      `yaml:"database"`
+	Validation    ValidationConfig    `yaml:"validation"`
 }
 
 // Структура конфигурации сервера
 type GeneralServerConfig struct {
-	Env      string          `yaml:"env" env-required:"true"`
-	LogLevel string          `yaml:"logLevel" env-default:"debug"`
-	DataSet  []models.Person `yaml:"persons"`
+	Env           string          `yaml:"env" env-required:"true"`
+	LogLevel      string          `yaml:"logLevel" env-default:"debug"`
+	SeedMode      string          `yaml:"seed"` // none, if-empty, upsert-by-email, reset
+	DataSet       []models.Person `yaml:"persons"`
+	Users         []models.User   `yaml:"users"`         // создаются при запуске, если отсутствуют
+	AnonymousRole string          `yaml:"anonymousRole"` // роль запросов без аутентификации; пусто - аутентификация обязательна
+	TokenMaxAge   time.Duration   `yaml:"tokenMaxAge"`   // допустимое отклонение Created в WS-Security UsernameToken
+
+	TombstoneRetention time.Duration `yaml:"tombstoneRetention"` // срок хранения удаленных записей; 0 - без очистки
+	PurgeInterval      time.Duration `yaml:"purgeInterval"`      // интервал очистки удаленных записей (по умолчанию 1h)
+
+	TrustedProxies []string `yaml:"trustedProxies"` // прокси, которым доверяется X-Forwarded-For; пусто - адрес соединения
 }
 
 // Структура конфигурации HTTP сервера
@@ -35,6 +45,8 @@ type HTTPServerConfig struct {
 
 // Структура конфигурации подключения к базе данных
 type DatabaseConfig struct {
+	Driver   string `yaml:"driver"` // postgres (по умолчанию), sqlite, memory
+	Path     string `yaml:"path"`   // файл базы данных для sqlite
 	Host     string `yaml:"host"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
@@ -43,12 +55,34 @@ type DatabaseConfig struct {
 	SSLMode  string `yaml:"sslMode"`
 }
 
+// Структура правил проверки полей Person
+type ValidationConfig struct {
+	Name      FieldRulesConfig `yaml:"name"`
+	Surname   FieldRulesConfig `yaml:"surname"`
+	Age       FieldRulesConfig `yaml:"age"`
+	Email     FieldRulesConfig `yaml:"email"`
+	Telephone FieldRulesConfig `yaml:"telephone"`
+}
+
+// Структура правил проверки поля; правила, не относящиеся к типу поля, не применяются
+type FieldRulesConfig struct {
+	Required       bool     `yaml:"required"`       // строковое поле не может быть пустым (email обязателен всегда)
+	MinLength      int      `yaml:"minLength"`      // минимальная длина в символах
+	MaxLength      int      `yaml:"maxLength"`      // максимальная длина в символах; 0 - размер столбца (200)
+	Patterns       []string `yaml:"patterns"`       // регулярные выражения, значение должно соответствовать одному из них
+	Min            *int     `yaml:"min"`            // минимальное значение числового поля
+	Max            *int     `yaml:"max"`            // максимальное значение числового поля
+	AllowedDomains []string `yaml:"allowedDomains"` // допустимые домены email (с поддоменами); пусто - любые
+	DeniedDomains  []string `yaml:"deniedDomains"`  // запрещенные домены email (с поддоменами)
+}
+
 // Переменные конфигурации
 var (
 	config               Config
 	GeneralServerSetting = &GeneralServerConfig{}
 	HTTPServerSetting    = &HTTPServerConfig{}
 	DatabaseSetting      = &DatabaseConfig{}
+	ValidationSetting    = &ValidationConfig{}
 )
 
 // Функция инициализации конфигурации
@@ -84,5 +118,6 @@ func Init() {
 	*GeneralServerSetting = config.GeneralServer
 	*HTTPServerSetting = config.HTTPServer
 	*DatabaseSetting = config.Database
+	*ValidationSetting = config.Validation
 
 }
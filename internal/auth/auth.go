@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUserDisabled       = errors.New("user disabled")
+	ErrEmptyPassword      = errors.New("empty password")
+	ErrNoCredentials      = errors.New("no credentials")
+)
+
+/*
+Хеш, с которым сравнивается пароль неизвестного пользователя, чтобы время
+ответа не позволяло отличить несуществующее имя от неверного пароля
+*/
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+/*
+Функция хеширования пароля (bcrypt)
+*/
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", ErrEmptyPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+/*
+Функция проверки пароля по хешу; сравнение bcrypt выполняется за постоянное время
+*/
+func CheckPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+/*
+Функция аутентификации пользователя по имени и паролю
+*/
+func Authenticate(users database.UserRepository, username string, password string) (*models.User, error) {
+	user, err := users.GetUser(username)
+	if errors.Is(err, database.ErrUserNotFound) {
+		CheckPassword(string(dummyHash), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !CheckPassword(user.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+	return user, nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+Переменная окружения с ключом шифрования секретов PasswordDigest:
+32 байта AES-256 в base64 (например, результат openssl rand -base64 32)
+*/
+const DigestKeyEnv = "WST_DIGEST_KEY"
+
+// Префикс зашифрованного секрета: версия формата хранения
+const encryptedSecretPrefix = "v1:"
+
+/*
+Максимальная длина секрета PasswordDigest в байтах: зашифрованное значение
+должно поместиться в столбец users.digest_secret (varchar(200))
+*/
+const MaxDigestSecretLength = 100
+
+var (
+	ErrNoDigestKey        = errors.New("digest key is not configured (" + DigestKeyEnv + ")")
+	ErrLegacyDigestSecret = errors.New("digest secret is stored unencrypted")
+	ErrInvalidDigestKey   = errors.New("digest key must be 32 bytes encoded in base64")
+)
+
+/*
+Чтение ключа шифрования секретов из переменной окружения WST_DIGEST_KEY.
+Без переменной возвращает nil без ошибки: PasswordDigest в этом случае недоступен
+*/
+func DigestKeyFromEnv() ([]byte, error) {
+	value := strings.TrimSpace(os.Getenv(DigestKeyEnv))
+	if value == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidDigestKey
+	}
+	return key, nil
+}
+
+// Хранится ли секрет в открытом виде (до введения шифрования)
+func IsLegacyDigestSecret(stored string) bool {
+	return stored != "" && !strings.HasPrefix(stored, encryptedSecretPrefix)
+}
+
+func digestCipher(key []byte) (cipher.AEAD, error) {
+	if key == nil {
+		return nil, ErrNoDigestKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidDigestKey
+	}
+	return cipher.NewGCM(block)
+}
+
+/*
+Шифрование секрета PasswordDigest ключом сервера (AES-GCM).
+Результат: "v1:" + base64(nonce + шифртекст)
+*/
+func EncryptDigestSecret(key []byte, secret string) (string, error) {
+	if len(secret) > MaxDigestSecretLength {
+		return "", fmt.Errorf("digest secret is longer than %d bytes", MaxDigestSecretLength)
+	}
+	aead, err := digestCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+/*
+Расшифровка сохраненного секрета. Секрет в открытом виде отклоняется (ErrLegacyDigestSecret):
+его нужно задать заново командой user digest
+*/
+func DecryptDigestSecret(key []byte, stored string) (string, error) {
+	if IsLegacyDigestSecret(stored) {
+		return "", ErrLegacyDigestSecret
+	}
+	aead, err := digestCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedSecretPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed digest secret")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting digest secret: %v", err)
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"slices"
+)
+
+/*
+Разрешение на выполнение группы операций
+*/
+type Permission string
+
+const (
+	PermissionRead   Permission = "persons:read"
+	PermissionWrite  Permission = "persons:write"
+	PermissionDelete Permission = "persons:delete"
+	PermissionAudit  Permission = "persons:audit"
+)
+
+/*
+Разрешения каждой роли
+*/
+var RolePermissions = map[string][]Permission{
+	models.RoleReader: {PermissionRead},
+	models.RoleEditor: {PermissionRead, PermissionWrite},
+	models.RoleAdmin:  {PermissionRead, PermissionWrite, PermissionDelete, PermissionAudit},
+}
+
+/*
+Функция проверки наличия разрешения у роли
+*/
+func HasPermission(role string, permission Permission) bool {
+	return slices.Contains(RolePermissions[role], permission)
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Допустимое отклонение времени Created от времени сервера по умолчанию
+const DefaultTokenMaxAge = 5 * time.Minute
+
+var (
+	ErrUnsupportedPasswordType = errors.New("unsupported password type")
+	ErrDigestNotAllowed        = errors.New("password digest is not configured for user")
+	ErrTokenExpired            = errors.New("token created time is outside the allowed window")
+	ErrNonceReused             = errors.New("nonce already used")
+	ErrInvalidToken            = errors.New("invalid username token")
+)
+
+// Секрет, по которому вычисляется дайджест для неизвестного пользователя
+const dummyDigestSecret = "dummy digest secret"
+
+// Минимальный интервал между очистками устаревших Nonce
+const nonceSweepInterval = time.Minute
+
+/*
+Кэш использованных Nonce для защиты от повторной отправки UsernameToken.
+Nonce хранится, пока токен с ним может пройти проверку времени Created.
+Устаревшие записи удаляются не чаще раза в nonceSweepInterval, а не при каждом вызове
+*/
+type NonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	nextSweep time.Time
+}
+
+func NewNonceCache() *NonceCache {
+	return &NonceCache{seen: map[string]time.Time{}}
+}
+
+// Отмечает nonce использованным до expires; false, если nonce уже использовался
+func (n *NonceCache) Use(nonce string, expires time.Time, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if now.After(n.nextSweep) {
+		for key, expiry := range n.seen {
+			if now.After(expiry) {
+				delete(n.seen, key)
+			}
+		}
+		n.nextSweep = now.Add(nonceSweepInterval)
+	}
+	//Запись могла устареть после последней очистки
+	if expiry, ok := n.seen[nonce]; ok && !now.After(expiry) {
+		return false
+	}
+	n.seen[nonce] = expires
+	return true
+}
+
+/*
+Проверка UsernameToken из заголовка wsse:Security
+*/
+type TokenAuthenticator struct {
+	Users     database.UserRepository
+	Nonces    *NonceCache
+	MaxAge    time.Duration
+	Now       func() time.Time
+	DigestKey []byte // ключ шифрования секретов PasswordDigest; nil - PasswordDigest недоступен
+}
+
+/*
+Аутентификация по UsernameToken. PasswordText проверяется по bcrypt хешу пароля,
+PasswordDigest - по секрету DigestSecret пользователя, расшифрованному ключом DigestKey.
+Секрет в открытом виде и секрет без ключа не принимаются. Created должен отличаться
+от времени сервера не более чем на MaxAge, Nonce не должен повторяться.
+Для PasswordDigest Nonce и Created обязательны
+*/
+func (a *TokenAuthenticator) Authenticate(token *models.UsernameToken) (*models.User, error) {
+	now := time.Now()
+	if a.Now != nil {
+		now = a.Now()
+	}
+	maxAge := a.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultTokenMaxAge
+	}
+	passwordType := token.Password.Type
+	if passwordType == "" {
+		passwordType = models.PasswordTypeText
+	}
+	if passwordType != models.PasswordTypeText && passwordType != models.PasswordTypeDigest {
+		return nil, ErrUnsupportedPasswordType
+	}
+	if token.Username == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var nonce []byte
+	if token.Nonce != "" {
+		var err error
+		if nonce, err = base64.StdEncoding.DecodeString(token.Nonce); err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+	if passwordType == models.PasswordTypeDigest && (nonce == nil || token.Created == "") {
+		return nil, ErrInvalidToken
+	}
+	if token.Created != "" {
+		created, err := time.Parse(time.RFC3339, token.Created)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		if created.Before(now.Add(-maxAge)) || created.After(now.Add(maxAge)) {
+			return nil, ErrTokenExpired
+		}
+	}
+	if nonce != nil && !a.Nonces.Use(token.Username+"\x00"+token.Nonce, now.Add(2*maxAge), now) {
+		return nil, ErrNonceReused
+	}
+
+	if passwordType == models.PasswordTypeText {
+		return Authenticate(a.Users, token.Username, token.Password.Value)
+	}
+	user, err := a.Users.GetUser(token.Username)
+	if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+		return nil, err
+	}
+	//Для неизвестного пользователя и пользователя без секрета дайджест тоже вычисляется,
+	//чтобы время ответа не выдавало существование имени
+	if user == nil || user.DigestSecret == "" {
+		CheckPasswordDigest(token.Password.Value, nonce, token.Created, dummyDigestSecret)
+		if user == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, ErrDigestNotAllowed
+	}
+	secret, err := DecryptDigestSecret(a.DigestKey, user.DigestSecret)
+	if err != nil {
+		CheckPasswordDigest(token.Password.Value, nonce, token.Created, dummyDigestSecret)
+		return nil, fmt.Errorf("%w: %w", ErrDigestNotAllowed, err)
+	}
+	if !CheckPasswordDigest(token.Password.Value, nonce, token.Created, secret) {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+	return user, nil
+}
+
+/*
+Функция проверки PasswordDigest = Base64(SHA-1(nonce + created + secret)) со сравнением за постоянное время
+*/
+func CheckPasswordDigest(digest string, nonce []byte, created string, secret string) bool {
+	hash := sha1.New()
+	hash.Write(nonce)
+	hash.Write([]byte(created))
+	hash.Write([]byte(secret))
+	expected := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(digest)) == 1
+}
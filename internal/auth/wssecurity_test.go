@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"WST_lab1_server_new1/internal/database/memory"
+	"WST_lab1_server_new1/internal/models"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNonceCacheRejectsReplay(t *testing.T) {
+	cache := NewNonceCache()
+	now := time.Now()
+	if !cache.Use("nonce", now.Add(time.Minute), now) {
+		t.Fatal("first use of nonce rejected")
+	}
+	if cache.Use("nonce", now.Add(time.Minute), now.Add(time.Second)) {
+		t.Error("replayed nonce accepted")
+	}
+	//После истечения срока nonce снова допустим, даже если очистка еще не выполнялась
+	if !cache.Use("nonce", now.Add(3*time.Minute), now.Add(2*time.Minute)) {
+		t.Error("expired nonce rejected")
+	}
+}
+
+func TestNonceCacheSweepsExpiredEntries(t *testing.T) {
+	cache := NewNonceCache()
+	now := time.Now()
+	cache.Use("old", now.Add(time.Second), now)
+	cache.Use("new", now.Add(time.Hour), now.Add(nonceSweepInterval/2))
+	if len(cache.seen) != 2 {
+		t.Fatalf("entries before sweep = %d, want 2", len(cache.seen))
+	}
+	cache.Use("next", now.Add(time.Hour), now.Add(2*nonceSweepInterval))
+	if _, ok := cache.seen["old"]; ok || len(cache.seen) != 2 {
+		t.Errorf("expired entry was not swept: %v", cache.seen)
+	}
+}
+
+func TestDigestSecretEncryption(t *testing.T) {
+	key := make([]byte, 32)
+	stored, err := EncryptDigestSecret(key, "digest secret")
+	if err != nil {
+		t.Fatalf("EncryptDigestSecret: %v", err)
+	}
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) || strings.Contains(stored, "digest secret") {
+		t.Errorf("secret is not encrypted: %q", stored)
+	}
+	if secret, err := DecryptDigestSecret(key, stored); err != nil || secret != "digest secret" {
+		t.Errorf("DecryptDigestSecret = %q, %v", secret, err)
+	}
+	if _, err := DecryptDigestSecret(nil, stored); !errors.Is(err, ErrNoDigestKey) {
+		t.Errorf("decrypt without key: got %v, want %v", err, ErrNoDigestKey)
+	}
+	otherKey := make([]byte, 32)
+	otherKey[0] = 1
+	if _, err := DecryptDigestSecret(otherKey, stored); err == nil {
+		t.Error("secret decrypted with another key")
+	}
+	if _, err := DecryptDigestSecret(key, "digest secret"); !errors.Is(err, ErrLegacyDigestSecret) {
+		t.Errorf("decrypt plaintext secret: got %v, want %v", err, ErrLegacyDigestSecret)
+	}
+	if _, err := EncryptDigestSecret(key, strings.Repeat("x", MaxDigestSecretLength+1)); err == nil {
+		t.Error("too long secret encrypted")
+	}
+}
+
+func TestDigestKeyFromEnv(t *testing.T) {
+	t.Setenv(DigestKeyEnv, "")
+	if key, err := DigestKeyFromEnv(); key != nil || err != nil {
+		t.Errorf("unset key: got %v, %v", key, err)
+	}
+	t.Setenv(DigestKeyEnv, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	if _, err := DigestKeyFromEnv(); !errors.Is(err, ErrInvalidDigestKey) {
+		t.Errorf("short key: got %v, want %v", err, ErrInvalidDigestKey)
+	}
+	t.Setenv(DigestKeyEnv, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if key, err := DigestKeyFromEnv(); len(key) != 32 || err != nil {
+		t.Errorf("valid key: got %v, %v", key, err)
+	}
+}
+
+// UsernameToken с PasswordDigest для секрета secret
+func digestToken(username string, nonce string, created string, secret string) *models.UsernameToken {
+	hash := sha1.New()
+	hash.Write([]byte(nonce))
+	hash.Write([]byte(created))
+	hash.Write([]byte(secret))
+	token := &models.UsernameToken{Username: username, Nonce: base64.StdEncoding.EncodeToString([]byte(nonce)), Created: created}
+	token.Password.Type = models.PasswordTypeDigest
+	token.Password.Value = base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	return token
+}
+
+func TestAuthenticatePasswordDigest(t *testing.T) {
+	key := make([]byte, 32)
+	stored, err := EncryptDigestSecret(key, "digest secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := memory.NewUserRepository()
+	for _, user := range []models.User{
+		{Username: "encrypted", PasswordHash: "-", Role: models.RoleReader, DigestSecret: stored},
+		{Username: "legacy", PasswordHash: "-", Role: models.RoleReader, DigestSecret: "digest secret"},
+		{Username: "nodigest", PasswordHash: "-", Role: models.RoleReader},
+	} {
+		if _, err := users.AddUser(&user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created := time.Now().UTC().Format(time.RFC3339)
+	authenticator := &TokenAuthenticator{Users: users, Nonces: NewNonceCache(), DigestKey: key}
+
+	user, err := authenticator.Authenticate(digestToken("encrypted", "nonce1", created, "digest secret"))
+	if err != nil || user.Username != "encrypted" {
+		t.Errorf("encrypted secret: got %v, %v", user, err)
+	}
+	if _, err := authenticator.Authenticate(digestToken("encrypted", "nonce2", created, "wrong")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong secret: got %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := authenticator.Authenticate(digestToken("legacy", "nonce3", created, "digest secret")); !errors.Is(err, ErrLegacyDigestSecret) || !errors.Is(err, ErrDigestNotAllowed) {
+		t.Errorf("plaintext secret: got %v, want %v", err, ErrLegacyDigestSecret)
+	}
+	if _, err := authenticator.Authenticate(digestToken("nodigest", "nonce4", created, "")); !errors.Is(err, ErrDigestNotAllowed) {
+		t.Errorf("user without secret: got %v, want %v", err, ErrDigestNotAllowed)
+	}
+
+	//Без ключа сервера PasswordDigest недоступен
+	authenticator.DigestKey = nil
+	if _, err := authenticator.Authenticate(digestToken("encrypted", "nonce5", created, "digest secret")); !errors.Is(err, ErrNoDigestKey) {
+		t.Errorf("no key: got %v, want %v", err, ErrNoDigestKey)
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"strconv"
+	"time"
+)
+
+/*
+Автор изменений, выполняемых сервисом без запроса клиента (заполнение из конфигурации, очистка)
+*/
+var SystemActor = Actor{Username: "system"}
+
+// Поля записи, изменения которых сохраняются в журнале
+var auditFields = []string{"name", "surname", "age", "email", "telephone"}
+
+/*
+Функция сравнения состояний записи до и после операции: изменения по каждому полю.
+Отсутствующее состояние (nil) - запись добавлена или окончательно удалена
+*/
+func PersonChanges(before *models.Person, after *models.Person) []models.FieldChange {
+	fields := func(person *models.Person) []string {
+		if person == nil {
+			return make([]string, len(auditFields))
+		}
+		return []string{person.Name, person.Surname, strconv.Itoa(person.Age), person.Email, person.Telephone}
+	}
+	old, current := fields(before), fields(after)
+	changes := []models.FieldChange{}
+	for i, field := range auditFields {
+		if old[i] != current[i] {
+			changes = append(changes, models.FieldChange{Field: field, Before: old[i], After: current[i]})
+		}
+	}
+	return changes
+}
+
+/*
+Функция создания записи журнала для операции actor над записью personID,
+после которой запись имеет версию version
+*/
+func NewAuditEntry(actor Actor, operation string, personID uint, version uint, changes []models.FieldChange) models.AuditEntry {
+	return models.AuditEntry{
+		PersonID:  personID,
+		Version:   version,
+		Operation: operation,
+		Username:  actor.Username,
+		ClientIP:  actor.ClientIP,
+		MessageID: actor.MessageID,
+		Changes:   changes,
+		CreatedAt: time.Now().UTC(),
+	}
+}
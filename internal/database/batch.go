@@ -0,0 +1,49 @@
+package database
+
+import "errors"
+
+// Максимальное количество элементов пакетной операции
+const MaxBatchSize = 1000
+
+// Откат пакета atomic после ошибки элемента
+var errBatchRollback = errors.New("batch rolled back")
+
+/*
+Результат элемента пакета: ID записи или ошибка элемента
+*/
+type BatchResult struct {
+	ID  uint
+	Err error
+}
+
+/*
+Выполнение пакета из size элементов в одной транзакции. Каждый элемент выполняется
+в точке сохранения, поэтому ошибка элемента отменяет только его изменения.
+Режим atomic: при ошибке любого элемента транзакция откатывается целиком (committed = false);
+иначе фиксируются успешные элементы. Ошибка возвращается, только если не удалась сама транзакция
+*/
+func RunBatch(repo PersonRepository, atomic bool, size int, apply func(tx PersonRepository, i int) (uint, error)) (results []BatchResult, committed bool, err error) {
+	results = make([]BatchResult, size)
+	err = repo.Transaction(func(tx PersonRepository) error {
+		failed := false
+		for i := range results {
+			results[i].Err = tx.Transaction(func(item PersonRepository) error {
+				id, err := apply(item, i)
+				results[i].ID = id
+				return err
+			})
+			failed = failed || results[i].Err != nil
+		}
+		if failed && atomic {
+			return errBatchRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchRollback) {
+		return results, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
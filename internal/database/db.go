@@ -1,13 +1,78 @@
 package database
 
-import "errors"
+import (
+	"WST_lab1_server_new1/internal/models"
+	"errors"
+	"time"
+)
 
 var (
-	ErrPersonNotFound = errors.New("person not found")
-	ErrPersonExists   = errors.New("person exists")
-	ErrInvalidInput   = errors.New("invalid input")
-	ErrEmptyQuery     = errors.New("empty query")
-	ErrQueryTooLong   = errors.New("query too long")
-	ErrEmailExists    = errors.New("email exists")
-	
+	ErrPersonNotFound  = errors.New("person not found")
+	ErrPersonExists    = errors.New("person exists")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrEmptyQuery      = errors.New("empty query")
+	ErrQueryTooLong    = errors.New("query too long")
+	ErrEmailExists     = errors.New("email exists")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUserExists      = errors.New("user exists")
+	ErrVersionConflict = errors.New("version conflict")
 )
+
+/*
+Интерфейс хранилища записей Person. Реализации обязаны соблюдать одинаковую семантику:
+уникальность email (ErrEmailExists), ErrPersonNotFound для отсутствующих записей
+и ErrVersionConflict при обновлении записи с устаревшей версией.
+DeletePerson помечает запись удаленной: такие записи не видны остальным методам,
+пока не восстановлены RestorePerson, и окончательно удаляются PurgePerson или PurgeDeleted.
+Transaction выполняет fn в транзакции (вложенный вызов - точка сохранения):
+при ошибке fn все изменения, сделанные через tx, отменяются.
+WithActor возвращает хранилище, изменения через которое выполняются от имени actor;
+каждое изменение записывается в журнал (PersonHistory) в той же транзакции
+*/
+type PersonRepository interface {
+	AddPerson(person *models.Person) (uint, error)
+	GetPerson(id uint) (*models.Person, error)
+	UpdatePerson(person *models.Person) error
+	DeletePerson(id uint) error
+	RestorePerson(id uint) (*models.Person, error)
+	PurgePerson(id uint) error
+	PurgeDeleted(before time.Time) (int64, error)
+	DeleteAllPersons() (int64, error)
+	GetAllPersons(page PageRequest) (Page, error)
+	SearchPerson(query SearchQuery, page PageRequest) (Page, error)
+	RankedSearch(text string, page PageRequest) (Page, error)
+	CheckPersonByEmail(email string, excludeId uint) (*models.Person, error)
+	CheckPersonByID(id uint) (bool, error)
+	PersonHistory(id uint) ([]models.AuditEntry, error)
+	Transaction(fn func(tx PersonRepository) error) error
+	WithActor(actor Actor) PersonRepository
+}
+
+/*
+Автор изменения: пользователь, от имени которого выполняется запрос,
+адрес клиента и MessageID запроса
+*/
+type Actor struct {
+	Username  string
+	ClientIP  string
+	MessageID string
+}
+
+/*
+Интерфейс хранилища пользователей. Имя пользователя уникально (ErrUserExists),
+отсутствующий пользователь - ErrUserNotFound
+*/
+type UserRepository interface {
+	AddUser(user *models.User) (uint, error)
+	GetUser(username string) (*models.User, error)
+	UpdateUser(user *models.User) error
+	ListUsers() ([]models.User, error)
+}
+
+/*
+Хранилище, возвращаемое инициализацией выбранного в конфигурации драйвера
+*/
+type Storage struct {
+	PersonRepository PersonRepository
+	UserRepository   UserRepository
+}
@@ -0,0 +1,29 @@
+package gormdb
+
+import (
+	"errors"
+
+	sqlitedriver "github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Код SQLSTATE нарушения уникальности в PostgreSQL
+const pgUniqueViolation = "23505"
+
+/*
+Проверка нарушения уникального индекса: SQLSTATE 23505 в PostgreSQL,
+SQLITE_CONSTRAINT_UNIQUE в SQLite. Индекс, а не предварительная проверка,
+гарантирует уникальность при одновременных запросах
+*/
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	var sqliteErr *sqlitedriver.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package gormdb
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/database/migrations"
+	"WST_lab1_server_new1/internal/logging"
+
+	"context"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+/*
+Логгер gorm с уровнем из файла конфигурации
+*/
+func Logger() logger.Interface {
+	var logLevel logger.LogLevel
+	switch config.GeneralServerSetting.LogLevel {
+	case "fatal":
+		logLevel = logger.Silent
+	case "error":
+		logLevel = logger.Error
+	case "warn":
+		logLevel = logger.Warn
+	case "info", "debug":
+		logLevel = logger.Info
+	default:
+		logLevel = logger.Info
+	}
+	return logger.Default.LogMode(logLevel)
+}
+
+/*
+Исполнитель миграций для открытой базы данных (драйвер определяется по gorm)
+*/
+func Migrator(db *gorm.DB) (*migrations.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB, db.Dialector.Name())
+}
+
+/*
+Применение всех ожидающих миграций
+*/
+func Migrate(db *gorm.DB) error {
+	migrator, err := Migrator(db)
+	if err != nil {
+		return err
+	}
+	return migrator.Up(context.Background())
+}
+
+/*
+Подготовка открытой базы данных (миграция и заполнение) и создание хранилища
+*/
+func NewStorage(db *gorm.DB) (*database.Storage, error) {
+	//Применяем ожидающие миграции схемы
+	if err := Migrate(db); err != nil {
+		log.Fatalf("error migrating database: %v", err)
+		return nil, fmt.Errorf("error migrating database: %v", err)
+	}
+	logging.Logger.Info("Migration completed successfully.")
+	personRepo := &PersonRepository{DB: db}
+	//Заполняем таблицу из фаила конфигурации в режиме, заданном в конфигурации
+	_, err := database.Seed(personRepo.WithActor(database.SystemActor), config.GeneralServerSetting.SeedMode, config.GeneralServerSetting.DataSet)
+	if err != nil {
+		return nil, fmt.Errorf("error seeding database: %v", err)
+	}
+	userRepo := &UserRepository{DB: db}
+	//Создаем пользователей из файла конфигурации
+	if err := database.SeedUsers(userRepo, config.GeneralServerSetting.Users); err != nil {
+		return nil, fmt.Errorf("error seeding users: %v", err)
+	}
+
+	//Возвращаем указатель
+	return &database.Storage{
+		PersonRepository: personRepo,
+		UserRepository:   userRepo,
+	}, nil
+}
@@ -0,0 +1,512 @@
+package gormdb
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+Реализация database.PersonRepository через gorm, общая для PostgreSQL и SQLite.
+Actor - автор изменений, выполняемых через хранилище
+*/
+type PersonRepository struct {
+	DB    *gorm.DB
+	Actor database.Actor
+}
+
+// Условие отбора действующих (не удаленных) записей
+func alive(db *gorm.DB) *gorm.DB {
+	return db.Where("deleted_at IS NULL")
+}
+
+/*
+Метод получения хранилища, изменения через которое выполняются от имени actor
+*/
+func (pr *PersonRepository) WithActor(actor database.Actor) database.PersonRepository {
+	return &PersonRepository{DB: pr.DB, Actor: actor}
+}
+
+/*
+//
+Метод поиска в базе данных по запросу
+//
+*/
+func (pr *PersonRepository) SearchPerson(query database.SearchQuery, page database.PageRequest) (database.Page, error) {
+	if err := query.Validate(); err != nil {
+		return database.Page{}, err
+	}
+	//Формируем параметризованное условие по всем критериям запроса
+	var conditions []string
+	var args []any
+	for _, condition := range query.Text {
+		//Без учета регистра сравниваем значения, приведенные к нижнему регистру
+		column, placeholder := database.SearchColumns[condition.Field], "?"
+		if condition.IgnoreCase {
+			column, placeholder = "LOWER("+column+")", "LOWER(?)"
+		}
+		if condition.Op == database.OpEquals {
+			conditions = append(conditions, column+" = "+placeholder)
+			args = append(args, condition.Value)
+			continue
+		}
+		conditions = append(conditions, column+" LIKE "+placeholder+` ESCAPE '\'`)
+		args = append(args, condition.LikePattern())
+	}
+	if query.HasAge() {
+		var ages []string
+		if query.MinAge != nil {
+			ages = append(ages, "age >= ?")
+			args = append(args, *query.MinAge)
+		}
+		if query.MaxAge != nil {
+			ages = append(ages, "age <= ?")
+			args = append(args, *query.MaxAge)
+		}
+		conditions = append(conditions, "("+strings.Join(ages, " AND ")+")")
+	}
+	operator := " AND "
+	if query.Any {
+		operator = " OR "
+	}
+	where := "(" + strings.Join(conditions, operator) + ")"
+	filter := func(db *gorm.DB) *gorm.DB {
+		return db.Where(where, args...)
+	}
+	//Выполняем запрос и возвращаем страницу результата
+	return pr.page(filter, page)
+}
+
+/*
+Метод ранжированного поиска. В PostgreSQL - полнотекстовый поиск по search_vector
+и триграммное сходство имени и фамилии (pg_trgm) с вариантами написания запроса,
+в остальных базах - ранжирование всех записей в приложении
+*/
+func (pr *PersonRepository) RankedSearch(text string, page database.PageRequest) (database.Page, error) {
+	if pr.DB.Dialector.Name() != "postgres" {
+		var persons []models.Person
+		if err := pr.DB.Scopes(alive).Find(&persons).Error; err != nil {
+			return database.Page{}, err
+		}
+		return database.RankPersons(persons, text, page)
+	}
+
+	var result database.Page
+	if err := database.ValidateSearchText(text); err != nil {
+		return result, err
+	}
+	offset, err := page.Offset()
+	if err != nil {
+		return result, err
+	}
+	//Условие отбора (операторы @@ и % используют индексы) и выражение релевантности
+	conditions := []string{"search_vector @@ plainto_tsquery('simple', ?)"}
+	conditionArgs := []any{text}
+	var similarities []string
+	var scoreArgs []any
+	for _, variant := range database.SpellingVariants(text) {
+		for _, column := range []string{"name", "surname", "(name || ' ' || surname)"} {
+			conditions = append(conditions, column+" % ?")
+			conditionArgs = append(conditionArgs, variant)
+			similarities = append(similarities, "similarity("+column+", ?)")
+			scoreArgs = append(scoreArgs, variant)
+		}
+	}
+	where := "(" + strings.Join(conditions, " OR ") + ")"
+	score := "GREATEST(" + strings.Join(similarities, ", ") + ") + ts_rank(search_vector, plainto_tsquery('simple', ?))"
+	scoreArgs = append(scoreArgs, text)
+
+	if err := pr.DB.Model(&models.Person{}).Scopes(alive).Where(where, conditionArgs...).Count(&result.TotalCount).Error; err != nil {
+		return result, err
+	}
+	var rows []struct {
+		models.Person `gorm:"embedded"`
+		Score         float64
+	}
+	limit := page.Limit()
+	err = pr.DB.Model(&models.Person{}).
+		Select("people.id, people.name, people.surname, people.age, people.email, people.telephone, people.version, "+score+" AS score", scoreArgs...).
+		Scopes(alive).
+		Where(where, conditionArgs...).
+		Order("score DESC, id").
+		Offset(offset).
+		Limit(limit + 1).
+		Scan(&rows).Error
+	if err != nil {
+		return result, err
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		result.NextPageToken = database.NewOffsetToken(offset + limit)
+	}
+	for _, row := range rows {
+		result.Persons = append(result.Persons, row.Person)
+		result.Scores = append(result.Scores, row.Score)
+	}
+	return result, nil
+}
+
+/*
+Метод добавления новых данных. Проверка email, вставка и запись в журнал выполняются
+в одной транзакции; при гонке одновременных запросов дубликат отклоняет уникальный индекс по email
+*/
+func (pr *PersonRepository) AddPerson(person *models.Person) (uint, error) {
+	err := pr.DB.Transaction(func(tx *gorm.DB) error {
+		//Проверяем наличие записи с таким же email
+		if _, err := pr.in(tx).CheckPersonByEmail(person.Email, 0); err == nil {
+			return database.ErrEmailExists
+		}
+		//Создаем запись в базе данных с первой версией
+		person.Version = 1
+		if err := tx.Create(person).Error; err != nil {
+			if isUniqueViolation(err) {
+				return database.ErrEmailExists
+			}
+			return err
+		}
+		return pr.audit(tx, models.AuditAdd, person.ID, person.Version, database.PersonChanges(nil, person))
+	})
+	if err != nil {
+		return 0, err
+	}
+	//Возвращаем id созданной записи
+	return person.ID, nil
+}
+
+/*
+Метод получения данных по id
+*/
+func (pr *PersonRepository) GetPerson(id uint) (*models.Person, error) {
+	var person models.Person
+	//Выполняем запрос к базе данных для получения записи по id
+	err := pr.DB.Scopes(alive).First(&person, id).Error
+	if err != nil {
+		//Возвращаем ошибку при выполнении запроса к базе данных
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, database.ErrPersonNotFound
+		}
+		return nil, err
+	}
+	//Возвращаем результат
+	return &person, nil
+}
+
+/*
+Метод обновления данных по id и версии записи. Чтение текущего состояния, проверка email,
+обновление и запись в журнал выполняются в одной транзакции; при гонке одновременных запросов
+дубликат отклоняет уникальный индекс по email. Обновление с устаревшей версией - ErrVersionConflict,
+при успехе person.Version - новая версия
+*/
+func (pr *PersonRepository) UpdatePerson(person *models.Person) error {
+	return pr.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := pr.in(tx).GetPerson(person.ID)
+		if err != nil {
+			return err
+		}
+		if before.Version != person.Version {
+			return database.ErrVersionConflict
+		}
+		//Проверяем наличие другой записи с таким же email
+		if _, err := pr.in(tx).CheckPersonByEmail(person.Email, person.ID); err == nil {
+			return database.ErrEmailExists
+		}
+		//Выполняем запрос к базе данных для обновления записи; условие по версии исключает
+		//перезапись изменений, сделанных после чтения записи
+		result := tx.Model(&models.Person{}).Scopes(alive).Where("id = ? AND version = ?", person.ID, person.Version).Updates(map[string]any{
+			"name":      person.Name,
+			"surname":   person.Surname,
+			"age":       person.Age,
+			"email":     person.Email,
+			"telephone": person.Telephone,
+			"version":   gorm.Expr("version + 1"),
+		})
+
+		if result.Error != nil {
+			//Нарушение уникального индекса - запись с таким email добавлена параллельно
+			if isUniqueViolation(result.Error) {
+				return database.ErrEmailExists
+			}
+			//Возвращаем ошибку при выполнении запроса к базе данных
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			//Запись изменена или удалена параллельным запросом
+			return database.ErrVersionConflict
+		}
+		person.Version++
+		return pr.audit(tx, models.AuditUpdate, person.ID, person.Version, database.PersonChanges(before, person))
+	})
+}
+
+/*
+Метод удаления данных по id: запись помечается удаленной с указанием времени и автора
+*/
+func (pr *PersonRepository) DeletePerson(id uint) error {
+	return pr.DB.Transaction(func(tx *gorm.DB) error {
+		person, err := pr.in(tx).GetPerson(id)
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&models.Person{}).Scopes(alive).Where("id = ?", id).Updates(map[string]any{
+			"deleted_at": time.Now().UTC(),
+			"deleted_by": pr.Actor.Username,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return database.ErrPersonNotFound
+		}
+		return pr.audit(tx, models.AuditDelete, id, person.Version, []models.FieldChange{})
+	})
+}
+
+/*
+Метод восстановления удаленной записи. Если email занят действующей записью - ErrEmailExists.
+Восстановление увеличивает версию записи
+*/
+func (pr *PersonRepository) RestorePerson(id uint) (*models.Person, error) {
+	var person models.Person
+	err := pr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND deleted_at IS NOT NULL", id).First(&person).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return database.ErrPersonNotFound
+			}
+			return err
+		}
+		if _, err := pr.in(tx).CheckPersonByEmail(person.Email, id); err == nil {
+			return database.ErrEmailExists
+		}
+		err := tx.Model(&models.Person{}).Where("id = ?", id).Updates(map[string]any{
+			"deleted_at": nil,
+			"deleted_by": "",
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			if isUniqueViolation(err) {
+				return database.ErrEmailExists
+			}
+			return err
+		}
+		person.DeletedAt, person.DeletedBy = nil, ""
+		person.Version++
+		return pr.audit(tx, models.AuditRestore, id, person.Version, []models.FieldChange{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+/*
+Метод окончательного удаления записи, ранее помеченной удаленной.
+Последнее состояние записи сохраняется в журнале
+*/
+func (pr *PersonRepository) PurgePerson(id uint) error {
+	return pr.DB.Transaction(func(tx *gorm.DB) error {
+		var person models.Person
+		if err := tx.Where("id = ? AND deleted_at IS NOT NULL", id).First(&person).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return database.ErrPersonNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&models.Person{}, id).Error; err != nil {
+			return err
+		}
+		return pr.audit(tx, models.AuditPurge, id, person.Version, database.PersonChanges(&person, nil))
+	})
+}
+
+/*
+Метод окончательного удаления записей, удаленных раньше before
+*/
+func (pr *PersonRepository) PurgeDeleted(before time.Time) (int64, error) {
+	var ids []uint
+	if err := pr.DB.Model(&models.Person{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Order("id").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	var purged int64
+	for _, id := range ids {
+		//Запись могла быть восстановлена или очищена после выборки
+		if err := pr.PurgePerson(id); err != nil && !errors.Is(err, database.ErrPersonNotFound) {
+			return purged, err
+		} else if err == nil {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+/*
+Метод получения журнала изменений записи в порядке выполнения операций
+*/
+func (pr *PersonRepository) PersonHistory(id uint) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	if err := pr.DB.Where("person_id = ?", id).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Хранилище в транзакции tx с тем же автором изменений
+func (pr *PersonRepository) in(tx *gorm.DB) *PersonRepository {
+	return &PersonRepository{DB: tx, Actor: pr.Actor}
+}
+
+// Запись операции в журнал изменений (в транзакции изменения)
+func (pr *PersonRepository) audit(tx *gorm.DB, operation string, personID uint, version uint, changes []models.FieldChange) error {
+	entry := database.NewAuditEntry(pr.Actor, operation, personID, version, changes)
+	return tx.Create(&entry).Error
+}
+
+/*
+Метод удаления всех данных (включая удаленные записи), возвращает количество удаленных записей.
+Каждая запись попадает в журнал изменений как окончательно удаленная (purge)
+*/
+func (pr *PersonRepository) DeleteAllPersons() (int64, error) {
+	var deleted int64
+	err := pr.DB.Transaction(func(tx *gorm.DB) error {
+		var persons []models.Person
+		if err := tx.Order("id").Find(&persons).Error; err != nil {
+			return err
+		}
+		result := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Person{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		for _, person := range persons {
+			if err := pr.audit(tx, models.AuditPurge, person.ID, person.Version, database.PersonChanges(&person, nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+/*
+Метод получения всех данных
+*/
+func (pr *PersonRepository) GetAllPersons(page database.PageRequest) (database.Page, error) {
+	//Выполняем запрос к базе данных для получения страницы записей
+	return pr.page(func(query *gorm.DB) *gorm.DB { return query }, page)
+}
+
+/*
+Метод постраничной выборки: общее количество по фильтру и keyset страница
+по паре (поле сортировки, id) без смещения OFFSET
+*/
+func (pr *PersonRepository) page(filter func(*gorm.DB) *gorm.DB, page database.PageRequest) (database.Page, error) {
+	var result database.Page
+	cursor, err := page.Cursor()
+	if err != nil {
+		return result, err
+	}
+	//Общее количество записей по фильтру
+	if err := pr.DB.Model(&models.Person{}).Scopes(alive, filter).Count(&result.TotalCount).Error; err != nil {
+		return result, err
+	}
+
+	column := sortExpression(page.Column())
+	direction, operator := "ASC", ">"
+	if page.Descending {
+		direction, operator = "DESC", "<"
+	}
+	query := pr.DB.Model(&models.Person{}).Scopes(alive, filter)
+	if cursor != nil {
+		if column == "id" {
+			query = query.Where("id "+operator+" ?", cursor.ID)
+		} else {
+			query = query.Where("("+column+", id) "+operator+" (?, ?)", cursor.Value(), cursor.ID)
+		}
+	}
+	order := column + " " + direction
+	if column != "id" {
+		order += ", id " + direction
+	}
+	//Запрашиваем на одну запись больше, чтобы определить наличие следующей страницы
+	limit := page.Limit()
+	var persons []models.Person
+	if err := query.Order(order).Limit(limit + 1).Find(&persons).Error; err != nil {
+		return result, err
+	}
+	if len(persons) > limit {
+		persons = persons[:limit]
+		result.NextPageToken = database.NewPageToken(page, persons[limit-1])
+	}
+	result.Persons = persons
+	return result, nil
+}
+
+/*
+Выражение столбца сортировки. Столбцы name, surname и age допускают NULL, а сравнение
+(column, id) > (?, ?) с NULL не истинно, и такие записи терялись бы между страницами.
+NULL упорядочивается как пустая строка или 0 - так же, как в хранилище в памяти
+*/
+func sortExpression(column string) string {
+	switch column {
+	case "name", "surname":
+		return "COALESCE(" + column + ", '')"
+	case "age":
+		return "COALESCE(age, 0)"
+	}
+	return column
+}
+
+/*
+Метод выполнения fn в транзакции. Внутри транзакции gorm создает
+для вложенного вызова точку сохранения (SAVEPOINT)
+*/
+func (pr *PersonRepository) Transaction(fn func(tx database.PersonRepository) error) error {
+	return pr.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(pr.in(tx))
+	})
+}
+
+/*
+Метод проверки наличия записи по email
+*/
+func (pr *PersonRepository) CheckPersonByEmail(email string, excludeId uint) (*models.Person, error) {
+	var person models.Person
+	// Выполняем запрос к базе данных для поиска по email
+	if err := pr.DB.Scopes(alive).Where("email = ? AND id != ?", email, excludeId).First(&person).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			//Возвращаем кастомную ошибку (Запись не найдена)
+			return nil, database.ErrPersonNotFound
+		}
+
+		//Возвращаем ошибку
+		return nil, err
+	}
+	//Возвращаем запись
+
+	return &person, nil
+}
+
+/*
+Метод проверки наличия записи по id
+*/
+func (pr *PersonRepository) CheckPersonByID(id uint) (bool, error) {
+	var person models.Person
+	//Выполняем запрос к базе данных для поиска по id
+	result := pr.DB.Scopes(alive).First(&person, id)
+	if result.Error != nil {
+		//Проверяем наличие записи по id
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return false, database.ErrPersonNotFound
+		}
+		return false, result.Error
+	}
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package gormdb
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+/*
+Реализация database.UserRepository через gorm
+*/
+type UserRepository struct {
+	DB *gorm.DB
+}
+
+/*
+Метод добавления пользователя
+*/
+func (ur *UserRepository) AddUser(user *models.User) (uint, error) {
+	if _, err := ur.GetUser(user.Username); err == nil {
+		return 0, database.ErrUserExists
+	}
+	if err := ur.DB.Create(user).Error; err != nil {
+		if isUniqueViolation(err) {
+			return 0, database.ErrUserExists
+		}
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+/*
+Метод получения пользователя по имени
+*/
+func (ur *UserRepository) GetUser(username string) (*models.User, error) {
+	var user models.User
+	if err := ur.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, database.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+/*
+Метод обновления хеша пароля, секрета PasswordDigest, роли и блокировки пользователя
+*/
+func (ur *UserRepository) UpdateUser(user *models.User) error {
+	result := ur.DB.Model(&models.User{}).Where("username = ?", user.Username).Updates(map[string]any{
+		"password_hash": user.PasswordHash,
+		"digest_secret": user.DigestSecret,
+		"role":          user.Role,
+		"disabled":      user.Disabled,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return database.ErrUserNotFound
+	}
+	return nil
+}
+
+/*
+Метод получения всех пользователей
+*/
+func (ur *UserRepository) ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := ur.DB.Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
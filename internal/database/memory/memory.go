@@ -0,0 +1,371 @@
+package memory
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/logging"
+	"WST_lab1_server_new1/internal/models"
+
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+/*
+Реализация database.PersonRepository в памяти процесса.
+Используется для запуска сервиса и тестов без сервера базы данных.
+Удаленные записи хранятся с заполненным DeletedAt до окончательной очистки
+*/
+type PersonRepository struct {
+	*personStore
+	actor database.Actor
+}
+
+// Записи и журнал изменений, общие для хранилищ с разными авторами изменений
+type personStore struct {
+	mu          sync.RWMutex
+	persons     map[uint]models.Person
+	lastID      uint
+	audit       []models.AuditEntry
+	lastAuditID uint
+}
+
+func NewPersonRepository() *PersonRepository {
+	return &PersonRepository{personStore: &personStore{persons: map[uint]models.Person{}}}
+}
+
+/*
+Метод получения хранилища, изменения через которое выполняются от имени actor
+*/
+func (pr *PersonRepository) WithActor(actor database.Actor) database.PersonRepository {
+	return &PersonRepository{personStore: pr.personStore, actor: actor}
+}
+
+/*
+Действующая (не удаленная) запись по id (вызывается под mu)
+*/
+func (pr *PersonRepository) alive(id uint) (models.Person, bool) {
+	person, ok := pr.persons[id]
+	return person, ok && person.DeletedAt == nil
+}
+
+/*
+Запись операции в журнал изменений (вызывается под mu)
+*/
+func (pr *PersonRepository) record(operation string, personID uint, version uint, changes []models.FieldChange) {
+	pr.lastAuditID++
+	entry := database.NewAuditEntry(pr.actor, operation, personID, version, changes)
+	entry.ID = pr.lastAuditID
+	pr.audit = append(pr.audit, entry)
+}
+
+/*
+Инициализация
+*/
+func Init() (*database.Storage, error) {
+	personRepo := NewPersonRepository()
+	//Заполняем хранилище из фаила конфигурации
+	_, err := database.Seed(personRepo.WithActor(database.SystemActor), config.GeneralServerSetting.SeedMode, config.GeneralServerSetting.DataSet)
+	if err != nil {
+		return nil, err
+	}
+	userRepo := NewUserRepository()
+	if err := database.SeedUsers(userRepo, config.GeneralServerSetting.Users); err != nil {
+		return nil, err
+	}
+	logging.Logger.Info("In-memory storage initialized", zap.Int("persons", len(personRepo.persons)))
+	return &database.Storage{
+		PersonRepository: personRepo,
+		UserRepository:   userRepo,
+	}, nil
+}
+
+/*
+Проверка занятости email без блокировки (вызывается под mu)
+*/
+func (pr *PersonRepository) emailTaken(email string, excludeId uint) (models.Person, bool) {
+	for id, person := range pr.persons {
+		if id != excludeId && person.Email == email && person.DeletedAt == nil {
+			return person, true
+		}
+	}
+	return models.Person{}, false
+}
+
+/*
+Постраничная выборка записей, удовлетворяющих match (вызывается под mu)
+*/
+func (pr *PersonRepository) page(match func(models.Person) bool, page database.PageRequest) (database.Page, error) {
+	var result database.Page
+	cursor, err := page.Cursor()
+	if err != nil {
+		return result, err
+	}
+	column := page.Column()
+	persons := make([]models.Person, 0, len(pr.persons))
+	for _, person := range pr.persons {
+		if person.DeletedAt != nil || !match(person) {
+			continue
+		}
+		result.TotalCount++
+		if cursor == nil || cursor.Precedes(person) {
+			persons = append(persons, person)
+		}
+	}
+	sort.Slice(persons, func(i, j int) bool {
+		if page.Descending {
+			return database.ComparePersons(persons[i], persons[j], column) > 0
+		}
+		return database.ComparePersons(persons[i], persons[j], column) < 0
+	})
+	if limit := page.Limit(); len(persons) > limit {
+		persons = persons[:limit]
+		result.NextPageToken = database.NewPageToken(page, persons[limit-1])
+	}
+	result.Persons = persons
+	return result, nil
+}
+
+/*
+Метод поиска по запросу
+*/
+func (pr *PersonRepository) SearchPerson(query database.SearchQuery, page database.PageRequest) (database.Page, error) {
+	if err := query.Validate(); err != nil {
+		return database.Page{}, err
+	}
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	return pr.page(query.Match, page)
+}
+
+/*
+Метод ранжированного поиска (ранжирование в приложении)
+*/
+func (pr *PersonRepository) RankedSearch(text string, page database.PageRequest) (database.Page, error) {
+	pr.mu.RLock()
+	persons := make([]models.Person, 0, len(pr.persons))
+	for _, person := range pr.persons {
+		if person.DeletedAt == nil {
+			persons = append(persons, person)
+		}
+	}
+	pr.mu.RUnlock()
+	return database.RankPersons(persons, text, page)
+}
+
+/*
+Метод добавления новых данных
+*/
+func (pr *PersonRepository) AddPerson(person *models.Person) (uint, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if _, taken := pr.emailTaken(person.Email, 0); taken {
+		return 0, database.ErrEmailExists
+	}
+	pr.lastID++
+	person.ID, person.Version = pr.lastID, 1
+	pr.persons[person.ID] = *person
+	pr.record(models.AuditAdd, person.ID, person.Version, database.PersonChanges(nil, person))
+	return person.ID, nil
+}
+
+/*
+Метод получения данных по id
+*/
+func (pr *PersonRepository) GetPerson(id uint) (*models.Person, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	person, ok := pr.alive(id)
+	if !ok {
+		return nil, database.ErrPersonNotFound
+	}
+	return &person, nil
+}
+
+/*
+Метод обновления данных по id и версии записи
+*/
+func (pr *PersonRepository) UpdatePerson(person *models.Person) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	existing, ok := pr.alive(person.ID)
+	if !ok {
+		return database.ErrPersonNotFound
+	}
+	//Версия проверяется до email, как в gormdb: устаревший запрос получает конфликт версий
+	if existing.Version != person.Version {
+		return database.ErrVersionConflict
+	}
+	if _, taken := pr.emailTaken(person.Email, person.ID); taken {
+		return database.ErrEmailExists
+	}
+	person.Version++
+	pr.persons[person.ID] = *person
+	pr.record(models.AuditUpdate, person.ID, person.Version, database.PersonChanges(&existing, person))
+	return nil
+}
+
+/*
+Метод удаления данных по id: запись помечается удаленной с указанием времени и автора
+*/
+func (pr *PersonRepository) DeletePerson(id uint) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	person, ok := pr.alive(id)
+	if !ok {
+		return database.ErrPersonNotFound
+	}
+	now := time.Now().UTC()
+	person.DeletedAt, person.DeletedBy = &now, pr.actor.Username
+	pr.persons[id] = person
+	pr.record(models.AuditDelete, id, person.Version, []models.FieldChange{})
+	return nil
+}
+
+/*
+Метод восстановления удаленной записи. Если email занят действующей записью - ErrEmailExists
+*/
+func (pr *PersonRepository) RestorePerson(id uint) (*models.Person, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	person, ok := pr.persons[id]
+	if !ok || person.DeletedAt == nil {
+		return nil, database.ErrPersonNotFound
+	}
+	if _, taken := pr.emailTaken(person.Email, id); taken {
+		return nil, database.ErrEmailExists
+	}
+	person.DeletedAt, person.DeletedBy = nil, ""
+	person.Version++
+	pr.persons[id] = person
+	pr.record(models.AuditRestore, id, person.Version, []models.FieldChange{})
+	return &person, nil
+}
+
+/*
+Метод окончательного удаления записи, ранее помеченной удаленной.
+Последнее состояние записи сохраняется в журнале
+*/
+func (pr *PersonRepository) PurgePerson(id uint) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	person, ok := pr.persons[id]
+	if !ok || person.DeletedAt == nil {
+		return database.ErrPersonNotFound
+	}
+	delete(pr.persons, id)
+	pr.record(models.AuditPurge, id, person.Version, database.PersonChanges(&person, nil))
+	return nil
+}
+
+/*
+Метод окончательного удаления записей, удаленных раньше before
+*/
+func (pr *PersonRepository) PurgeDeleted(before time.Time) (int64, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	//Записи очищаются по возрастанию id, чтобы журнал не зависел от порядка обхода map
+	var ids []uint
+	for id, person := range pr.persons {
+		if person.DeletedAt != nil && person.DeletedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		person := pr.persons[id]
+		delete(pr.persons, id)
+		pr.record(models.AuditPurge, id, person.Version, database.PersonChanges(&person, nil))
+	}
+	return int64(len(ids)), nil
+}
+
+/*
+Метод удаления всех данных (включая удаленные записи), возвращает количество удаленных записей.
+Каждая запись попадает в журнал изменений как окончательно удаленная (purge)
+*/
+func (pr *PersonRepository) DeleteAllPersons() (int64, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	deleted := int64(len(pr.persons))
+	for _, id := range slices.Sorted(maps.Keys(pr.persons)) {
+		person := pr.persons[id]
+		pr.record(models.AuditPurge, id, person.Version, database.PersonChanges(&person, nil))
+	}
+	pr.persons = map[uint]models.Person{}
+	return deleted, nil
+}
+
+/*
+Метод получения всех данных
+*/
+func (pr *PersonRepository) GetAllPersons(page database.PageRequest) (database.Page, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	return pr.page(func(models.Person) bool { return true }, page)
+}
+
+/*
+Метод выполнения fn в транзакции: fn работает с копией записей, которая при успехе
+заменяет исходные. Хранилище заблокировано до завершения транзакции
+*/
+func (pr *PersonRepository) Transaction(fn func(tx database.PersonRepository) error) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	tx := &PersonRepository{personStore: &personStore{
+		persons:     maps.Clone(pr.persons),
+		lastID:      pr.lastID,
+		audit:       slices.Clip(pr.audit),
+		lastAuditID: pr.lastAuditID,
+	}, actor: pr.actor}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	pr.persons, pr.lastID = tx.persons, tx.lastID
+	pr.audit, pr.lastAuditID = tx.audit, tx.lastAuditID
+	return nil
+}
+
+/*
+Метод получения журнала изменений записи в порядке выполнения операций
+*/
+func (pr *PersonRepository) PersonHistory(id uint) ([]models.AuditEntry, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	var entries []models.AuditEntry
+	for _, entry := range pr.audit {
+		if entry.PersonID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+/*
+Метод проверки наличия записи по email
+*/
+func (pr *PersonRepository) CheckPersonByEmail(email string, excludeId uint) (*models.Person, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	person, taken := pr.emailTaken(email, excludeId)
+	if !taken {
+		return nil, database.ErrPersonNotFound
+	}
+	return &person, nil
+}
+
+/*
+Метод проверки наличия записи по id
+*/
+func (pr *PersonRepository) CheckPersonByID(id uint) (bool, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	if _, ok := pr.alive(id); !ok {
+		return false, database.ErrPersonNotFound
+	}
+	return true, nil
+}
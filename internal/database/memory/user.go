@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Реализация database.UserRepository в памяти процесса
+*/
+type UserRepository struct {
+	mu     sync.RWMutex
+	users  map[string]models.User
+	lastID uint
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: map[string]models.User{}}
+}
+
+/*
+Метод добавления пользователя
+*/
+func (ur *UserRepository) AddUser(user *models.User) (uint, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	if _, ok := ur.users[user.Username]; ok {
+		return 0, database.ErrUserExists
+	}
+	ur.lastID++
+	user.ID = ur.lastID
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	ur.users[user.Username] = *user
+	return user.ID, nil
+}
+
+/*
+Метод получения пользователя по имени
+*/
+func (ur *UserRepository) GetUser(username string) (*models.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	user, ok := ur.users[username]
+	if !ok {
+		return nil, database.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+/*
+Метод обновления хеша пароля, секрета PasswordDigest, роли и блокировки пользователя
+*/
+func (ur *UserRepository) UpdateUser(user *models.User) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	existing, ok := ur.users[user.Username]
+	if !ok {
+		return database.ErrUserNotFound
+	}
+	existing.PasswordHash = user.PasswordHash
+	existing.DigestSecret = user.DigestSecret
+	existing.Role = user.Role
+	existing.Disabled = user.Disabled
+	existing.UpdatedAt = time.Now()
+	ur.users[user.Username] = existing
+	return nil
+}
+
+/*
+Метод получения всех пользователей
+*/
+func (ur *UserRepository) ListUsers() ([]models.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	users := make([]models.User, 0, len(ur.users))
+	for _, user := range ur.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
@@ -0,0 +1,305 @@
+package migrations
+
+import (
+	"WST_lab1_server_new1/internal/logging"
+
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+/*
+SQL миграции для каждого драйвера: <версия>_<имя>.up.sql и <версия>_<имя>.down.sql
+*/
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Ключ advisory lock PostgreSQL, под которым выполняются миграции
+const advisoryLockKey = 7094001
+
+/*
+Миграция схемы
+*/
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+/*
+Состояние миграции для команды status
+*/
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+/*
+Исполнитель миграций. Применение и откат выполняются в одной транзакции
+под блокировкой, чтобы два экземпляра сервиса не мигрировали одновременно
+*/
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// Интерфейс выполнения запросов, общий для *sql.Tx и *sql.Conn
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+/*
+Создание исполнителя миграций для драйвера postgres или sqlite
+*/
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Загрузка встроенных файлов миграций драйвера, упорядоченных по версии
+func load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q: %v", dialect, err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		number, title, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		content, err := fs.ReadFile(files, path.Join(dialect, name))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: title}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Последняя доступная версия схемы
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+/*
+Выполнение fn под блокировкой миграций в одной транзакции:
+pg_advisory_xact_lock для PostgreSQL, BEGIN IMMEDIATE для SQLite
+*/
+func (m *Migrator) withLock(ctx context.Context, fn func(q querier) error) error {
+	if m.dialect == "sqlite" {
+		conn, err := m.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			return fmt.Errorf("error locking database: %v", err)
+		}
+		if err := fn(conn); err != nil {
+			conn.ExecContext(ctx, "ROLLBACK")
+			return err
+		}
+		_, err = conn.ExecContext(ctx, "COMMIT")
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", advisoryLockKey); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error locking database: %v", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Создание таблицы schema_migrations и чтение примененных версий
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int]time.Time, error) {
+	_, err := q.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       VARCHAR(200) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Текущая версия схемы: максимальная примененная
+func currentVersion(applied map[int]time.Time) int {
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current
+}
+
+/*
+Применение всех ожидающих миграций
+*/
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+/*
+Откат последней примененной миграции
+*/
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(q querier) error {
+		applied, err := m.applied(ctx, q)
+		if err != nil {
+			return err
+		}
+		current := currentVersion(applied)
+		if current == 0 {
+			logging.Logger.Info("No migrations to roll back")
+			return nil
+		}
+		return m.migrate(ctx, q, applied, current-1)
+	})
+}
+
+/*
+Приведение схемы к версии target: применение или откат миграций
+*/
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if target < 0 || target > m.Latest() {
+		return fmt.Errorf("unknown migration version %d (latest is %d)", target, m.Latest())
+	}
+	return m.withLock(ctx, func(q querier) error {
+		applied, err := m.applied(ctx, q)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, q, applied, target)
+	})
+}
+
+func (m *Migrator) migrate(ctx context.Context, q querier, applied map[int]time.Time, target int) error {
+	//Применяем неприменные миграции до target по возрастанию
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > target {
+			continue
+		}
+		if _, err := q.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("migration %d (%s) up: %v", migration.Version, migration.Name, err)
+		}
+		_, err := q.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		logging.Logger.Info("Migration applied", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	}
+	//Откатываем примененные миграции выше target по убыванию
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+			continue
+		}
+		if _, err := q.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("migration %d (%s) down: %v", migration.Version, migration.Name, err)
+		}
+		if _, err := q.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+			return err
+		}
+		logging.Logger.Info("Migration rolled back", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	}
+	return nil
+}
+
+/*
+Состояние всех известных миграций
+*/
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(q querier) error {
+		applied, err := m.applied(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, Status{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
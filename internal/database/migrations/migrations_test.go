@@ -0,0 +1,140 @@
+package migrations_test
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database/migrations"
+	"WST_lab1_server_new1/internal/database/sqlite"
+	"WST_lab1_server_new1/internal/logging"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	config.GeneralServerSetting.LogLevel = "fatal"
+	os.Exit(m.Run())
+}
+
+/*
+Исполнитель миграций для пустой базы SQLite во временном каталоге теста
+*/
+func newMigrator(t *testing.T) (*migrations.Migrator, *sql.DB) {
+	t.Helper()
+	config.DatabaseSetting.Path = filepath.Join(t.TempDir(), "test.db")
+	db, err := sqlite.Open()
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	migrator, err := migrations.New(sqlDB, "sqlite")
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	return migrator, sqlDB
+}
+
+// Версия схемы по данным команды status: максимальная примененная миграция
+func appliedVersion(t *testing.T, migrator *migrations.Migrator) int {
+	t.Helper()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	version := 0
+	for _, status := range statuses {
+		if status.Applied {
+			if status.AppliedAt.IsZero() {
+				t.Errorf("migration %d applied without time", status.Version)
+			}
+			version = status.Version
+		}
+	}
+	return version
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	migrator, db := newMigrator(t)
+	ctx := context.Background()
+	if version := appliedVersion(t, migrator); version != 0 {
+		t.Fatalf("version of empty database = %d, want 0", version)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if version := appliedVersion(t, migrator); version != migrator.Latest() {
+		t.Fatalf("version after up = %d, want %d", version, migrator.Latest())
+	}
+	if !tableExists(t, db, "people") || !tableExists(t, db, "users") {
+		t.Fatal("tables are not created by up")
+	}
+	//Повторный up ничего не применяет
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("repeated up: %v", err)
+	}
+
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if version := appliedVersion(t, migrator); version != migrator.Latest()-1 {
+		t.Errorf("version after down = %d, want %d", version, migrator.Latest()-1)
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	migrator, db := newMigrator(t)
+	ctx := context.Background()
+
+	if err := migrator.To(ctx, 2); err != nil {
+		t.Fatalf("to 2: %v", err)
+	}
+	if version := appliedVersion(t, migrator); version != 2 {
+		t.Fatalf("version = %d, want 2", version)
+	}
+	if !tableExists(t, db, "people") || tableExists(t, db, "users") {
+		t.Error("schema at version 2 must contain people and no users")
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("to 0: %v", err)
+	}
+	if version := appliedVersion(t, migrator); version != 0 {
+		t.Errorf("version after to 0 = %d, want 0", version)
+	}
+	if tableExists(t, db, "people") || tableExists(t, db, "users") {
+		t.Error("tables remain after rolling back all migrations")
+	}
+	//Откат пустой схемы ничего не делает
+	if err := migrator.Down(ctx); err != nil {
+		t.Errorf("down on empty schema: %v", err)
+	}
+
+	if err := migrator.To(ctx, migrator.Latest()+1); err == nil {
+		t.Error("migration to unknown version succeeded")
+	}
+	if err := migrator.To(ctx, -1); err == nil {
+		t.Error("migration to negative version succeeded")
+	}
+}
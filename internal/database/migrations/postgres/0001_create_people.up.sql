@@ -0,0 +1,11 @@
+-- Таблица записей Person. IF NOT EXISTS позволяет принять базы, созданные AutoMigrate
+CREATE TABLE IF NOT EXISTS people (
+    id        BIGSERIAL PRIMARY KEY,
+    name      VARCHAR(200),
+    surname   VARCHAR(200),
+    age       BIGINT,
+    email     VARCHAR(200) NOT NULL,
+    telephone VARCHAR(200) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_people_email ON people (email);
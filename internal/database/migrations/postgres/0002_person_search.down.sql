@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_people_full_name_trgm;
+DROP INDEX IF EXISTS idx_people_surname_trgm;
+DROP INDEX IF EXISTS idx_people_name_trgm;
+DROP INDEX IF EXISTS idx_people_search_vector;
+ALTER TABLE people DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,12 @@
+-- Ранжированный поиск: полнотекстовый вектор по строковым полям и триграммные индексы имени
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE people ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        coalesce(name, '') || ' ' || coalesce(surname, '') || ' ' ||
+        coalesce(email, '') || ' ' || coalesce(telephone, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_people_search_vector ON people USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_people_name_trgm ON people USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_surname_trgm ON people USING GIN (surname gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_full_name_trgm ON people USING GIN ((name || ' ' || surname) gin_trgm_ops);
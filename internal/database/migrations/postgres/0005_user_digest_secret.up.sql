@@ -0,0 +1,2 @@
+-- Секрет для WS-Security PasswordDigest; пустое значение запрещает PasswordDigest
+ALTER TABLE users ADD COLUMN digest_secret VARCHAR(200) NOT NULL DEFAULT '';
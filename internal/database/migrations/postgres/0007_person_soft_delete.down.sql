@@ -0,0 +1,9 @@
+-- Удаленные записи могут нарушать полный уникальный индекс email
+DELETE FROM people WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_people_deleted_at;
+DROP INDEX IF EXISTS idx_people_email;
+CREATE UNIQUE INDEX idx_people_email ON people (email);
+
+ALTER TABLE people DROP COLUMN deleted_by;
+ALTER TABLE people DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+-- Мягкое удаление: время и автор удаления; NULL в deleted_at - действующая запись
+ALTER TABLE people ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE people ADD COLUMN deleted_by VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Email уникален только среди действующих записей, чтобы удаленная запись не блокировала его
+DROP INDEX IF EXISTS idx_people_email;
+CREATE UNIQUE INDEX idx_people_email ON people (email) WHERE deleted_at IS NULL;
+
+-- Выборка удаленных записей для очистки
+CREATE INDEX idx_people_deleted_at ON people (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE person_audit;
+DROP FUNCTION person_audit_append_only();
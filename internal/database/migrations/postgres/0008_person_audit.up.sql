@@ -0,0 +1,24 @@
+-- Журнал изменений записей Person. Записи только добавляются и сохраняются
+-- после окончательного удаления записи Person, поэтому внешнего ключа нет
+CREATE TABLE person_audit (
+    id         BIGSERIAL PRIMARY KEY,
+    person_id  BIGINT NOT NULL,
+    operation  VARCHAR(20) NOT NULL,
+    username   VARCHAR(100) NOT NULL DEFAULT '',
+    client_ip  VARCHAR(64) NOT NULL DEFAULT '',
+    message_id VARCHAR(200) NOT NULL DEFAULT '',
+    changes    TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_person_audit_person_id ON person_audit (person_id, id);
+
+-- Изменение и удаление записей журнала запрещены
+CREATE FUNCTION person_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'person_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER person_audit_append_only BEFORE UPDATE OR DELETE ON person_audit
+    FOR EACH ROW EXECUTE FUNCTION person_audit_append_only();
@@ -0,0 +1,22 @@
+-- Версия записи Person после операции: номер ревизии для чтения состояния на момент времени
+ALTER TABLE person_audit ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+
+-- Исходные ревизии записей, добавленных до ведения журнала: текущее состояние
+-- записи и, для удаленных записей, отметка удаления (прежние ревизии неизвестны)
+INSERT INTO person_audit (person_id, operation, username, changes, created_at, version)
+SELECT id, 'add', 'system', json_build_array(
+        json_build_object('field', 'name', 'after', name),
+        json_build_object('field', 'surname', 'after', surname),
+        json_build_object('field', 'age', 'after', age::text),
+        json_build_object('field', 'email', 'after', email),
+        json_build_object('field', 'telephone', 'after', telephone)
+    )::text, COALESCE(deleted_at, now()), version
+FROM people p
+WHERE NOT EXISTS (SELECT 1 FROM person_audit a WHERE a.person_id = p.id)
+ORDER BY id;
+
+INSERT INTO person_audit (person_id, operation, username, created_at, version)
+SELECT id, 'delete', deleted_by, deleted_at, version
+FROM people p
+WHERE deleted_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM person_audit a WHERE a.person_id = p.id AND a.operation <> 'add')
+ORDER BY id;
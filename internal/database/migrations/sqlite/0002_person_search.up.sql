@@ -0,0 +1,2 @@
+-- В SQLite ранжированный поиск выполняется в приложении; индекс ускоряет выборку имен
+CREATE INDEX IF NOT EXISTS idx_people_name_surname ON people (name, surname);
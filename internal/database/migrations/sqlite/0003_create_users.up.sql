@@ -0,0 +1,11 @@
+-- Пользователи сервиса; пароль хранится в виде bcrypt хеша
+CREATE TABLE IF NOT EXISTS users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    username      VARCHAR(100) NOT NULL,
+    password_hash VARCHAR(200) NOT NULL,
+    disabled      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at    DATETIME,
+    updated_at    DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
@@ -0,0 +1,3 @@
+-- Роль пользователя: reader, editor, admin. Существующие пользователи
+-- сохраняют полный доступ; роль новых пользователей задает приложение
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'admin';
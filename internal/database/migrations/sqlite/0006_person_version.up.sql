@@ -0,0 +1,2 @@
+-- Версия записи для оптимистической блокировки: UpdatePerson увеличивает ее на 1
+ALTER TABLE people ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,25 @@
+-- Журнал изменений записей Person. Записи только добавляются и сохраняются
+-- после окончательного удаления записи Person, поэтому внешнего ключа нет
+CREATE TABLE person_audit (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    person_id  INTEGER NOT NULL,
+    operation  VARCHAR(20) NOT NULL,
+    username   VARCHAR(100) NOT NULL DEFAULT '',
+    client_ip  VARCHAR(64) NOT NULL DEFAULT '',
+    message_id VARCHAR(200) NOT NULL DEFAULT '',
+    changes    TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_person_audit_person_id ON person_audit (person_id, id);
+
+-- Изменение и удаление записей журнала запрещены
+CREATE TRIGGER person_audit_no_update BEFORE UPDATE ON person_audit
+BEGIN
+    SELECT RAISE(ABORT, 'person_audit is append-only');
+END;
+
+CREATE TRIGGER person_audit_no_delete BEFORE DELETE ON person_audit
+BEGIN
+    SELECT RAISE(ABORT, 'person_audit is append-only');
+END;
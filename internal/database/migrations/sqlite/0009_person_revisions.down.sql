@@ -0,0 +1,2 @@
+-- Исходные ревизии остаются в журнале: изменение и удаление его записей запрещены
+ALTER TABLE person_audit DROP COLUMN version;
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Размер страницы по умолчанию и максимальный размер страницы
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// Порядок ранжированного поиска - по релевантности
+const SortScore = "score"
+
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrInvalidSort      = errors.New("invalid sort field or direction")
+)
+
+/*
+Поля сортировки и соответствующие им столбцы таблицы people
+*/
+var SortColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"surname":   "surname",
+	"age":       "age",
+	"email":     "email",
+	"telephone": "telephone",
+}
+
+/*
+Параметры запроса страницы: размер, токен продолжения и сортировка.
+Постраничный вывод - keyset по паре (поле сортировки, id)
+*/
+type PageRequest struct {
+	Size       int
+	Token      string
+	SortBy     string
+	Descending bool
+}
+
+/*
+Страница результатов. Scores - релевантность записей (только для ранжированного поиска)
+*/
+type Page struct {
+	Persons       []models.Person
+	Scores        []float64
+	TotalCount    int64
+	NextPageToken string
+}
+
+/*
+Позиция последней записи предыдущей страницы, кодируется в непрозрачный токен
+*/
+type Cursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Text       string `json:"t,omitempty"`
+	Number     int64  `json:"n,omitempty"`
+	ID         uint   `json:"id"`
+}
+
+/*
+Функция разбора параметров сортировки из запроса (поле и направление asc/desc)
+*/
+func NewPageRequest(size int, token string, sortBy string, sortOrder string) (PageRequest, error) {
+	page := PageRequest{Size: size, Token: token, SortBy: strings.ToLower(strings.TrimSpace(sortBy))}
+	if page.SortBy == "" {
+		page.SortBy = "id"
+	}
+	if _, ok := SortColumns[page.SortBy]; !ok {
+		return page, ErrInvalidSort
+	}
+	switch strings.ToLower(strings.TrimSpace(sortOrder)) {
+	case "", "asc":
+	case "desc":
+		page.Descending = true
+	default:
+		return page, ErrInvalidSort
+	}
+	return page, nil
+}
+
+// Размер страницы с учетом значения по умолчанию и ограничения
+func (p PageRequest) Limit() int {
+	switch {
+	case p.Size <= 0:
+		return DefaultPageSize
+	case p.Size > MaxPageSize:
+		return MaxPageSize
+	}
+	return p.Size
+}
+
+// Столбец сортировки (по умолчанию id)
+func (p PageRequest) Column() string {
+	if column, ok := SortColumns[p.SortBy]; ok {
+		return column
+	}
+	return "id"
+}
+
+/*
+Разбор токена продолжения. Токен действителен только для той же сортировки
+*/
+func (p PageRequest) Cursor() (*Cursor, error) {
+	if p.Token == "" {
+		return nil, nil
+	}
+	cursor, err := decodeCursor(p.Token)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.SortBy != p.Column() || cursor.Descending != p.Descending {
+		return nil, ErrInvalidPageToken
+	}
+	return cursor, nil
+}
+
+/*
+Смещение страницы ранжированного поиска. Результаты упорядочены по релевантности,
+поэтому токен хранит количество уже выданных записей
+*/
+func (p PageRequest) Offset() (int, error) {
+	if p.Token == "" {
+		return 0, nil
+	}
+	cursor, err := decodeCursor(p.Token)
+	if err != nil {
+		return 0, err
+	}
+	if cursor.SortBy != SortScore || cursor.Number < 0 {
+		return 0, ErrInvalidPageToken
+	}
+	return int(cursor.Number), nil
+}
+
+func decodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	return &cursor, nil
+}
+
+// Значение курсора для сравнения в запросе
+func (c *Cursor) Value() any {
+	switch c.SortBy {
+	case "id", "age":
+		return c.Number
+	}
+	return c.Text
+}
+
+/*
+Функция формирования токена продолжения по последней записи страницы
+*/
+func NewPageToken(p PageRequest, last models.Person) string {
+	cursor := Cursor{SortBy: p.Column(), Descending: p.Descending, ID: last.ID}
+	switch cursor.SortBy {
+	case "id":
+		cursor.Number = int64(last.ID)
+	case "age":
+		cursor.Number = int64(last.Age)
+	default:
+		cursor.Text = SortText(last, cursor.SortBy)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Функция формирования токена продолжения ранжированного поиска
+func NewOffsetToken(offset int) string {
+	data, _ := json.Marshal(Cursor{SortBy: SortScore, Number: int64(offset)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Значение строкового поля сортировки записи
+func SortText(person models.Person, column string) string {
+	switch column {
+	case "name":
+		return person.Name
+	case "surname":
+		return person.Surname
+	case "email":
+		return person.Email
+	case "telephone":
+		return person.Telephone
+	}
+	return ""
+}
+
+/*
+Функция сравнения записей по полю сортировки; при равенстве сравниваются id
+*/
+func ComparePersons(a, b models.Person, column string) int {
+	var result int
+	switch column {
+	case "id":
+	case "age":
+		result = compare(int64(a.Age), int64(b.Age))
+	default:
+		result = strings.Compare(SortText(a, column), SortText(b, column))
+	}
+	if result == 0 {
+		result = compare(int64(a.ID), int64(b.ID))
+	}
+	return result
+}
+
+// Находится ли запись после курсора в порядке сортировки
+func (c *Cursor) Precedes(person models.Person) bool {
+	var result int
+	switch c.SortBy {
+	case "id":
+	case "age":
+		result = compare(c.Number, int64(person.Age))
+	default:
+		result = strings.Compare(c.Text, SortText(person, c.SortBy))
+	}
+	if result == 0 {
+		result = compare(int64(c.ID), int64(person.ID))
+	}
+	if c.Descending {
+		return result > 0
+	}
+	return result < 0
+}
+
+func compare(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/logging"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Интервал запуска очистки по умолчанию
+const DefaultPurgeInterval = time.Hour
+
+/*
+Функция запуска периодической очистки: записи, удаленные раньше чем retention назад,
+удаляются окончательно. Первая очистка выполняется сразу. Возвращает функцию остановки
+*/
+func StartPurge(repo PersonRepository, retention time.Duration, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultPurgeInterval
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := repo.PurgeDeleted(time.Now().UTC().Add(-retention))
+			if err != nil {
+				logging.Logger.Error("Purge of deleted persons failed", zap.Error(err))
+			} else if purged > 0 {
+				logging.Logger.Info("Deleted persons purged", zap.Int64("purged", purged), zap.Duration("retention", retention))
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
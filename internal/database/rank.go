@@ -0,0 +1,188 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+Порог сходства триграмм, как pg_trgm.similarity_threshold по умолчанию
+*/
+const SimilarityThreshold = 0.3
+
+// Вес совпадения всех слов запроса (приближение ts_rank для хранилищ без PostgreSQL)
+const fullTextWeight = 0.1
+
+/*
+Проверка строки ранжированного поиска
+*/
+func ValidateSearchText(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ErrEmptyQuery
+	}
+	if utf8.RuneCountInString(text) > MaxQueryLength {
+		return ErrQueryTooLong
+	}
+	return nil
+}
+
+// Транслитерация кириллицы в латиницу
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// Обратная транслитерация: сначала сочетания букв, затем одиночные буквы
+var latinToCyrillic = strings.NewReplacer(
+	"shch", "щ", "zh", "ж", "kh", "х", "ts", "ц", "ch", "ч", "sh", "ш", "yu", "ю", "ya", "я", "yo", "ё",
+	"a", "а", "b", "б", "c", "к", "d", "д", "e", "е", "f", "ф", "g", "г", "h", "х", "i", "и",
+	"j", "й", "k", "к", "l", "л", "m", "м", "n", "н", "o", "о", "p", "п", "q", "к", "r", "р",
+	"s", "с", "t", "т", "u", "у", "v", "в", "w", "в", "x", "кс", "y", "и", "z", "з",
+)
+
+/*
+Варианты написания строки поиска: исходная строка, ее латинская
+и кириллическая транслитерации (без повторов, в нижнем регистре)
+*/
+func SpellingVariants(text string) []string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	var latin strings.Builder
+	for _, r := range text {
+		if value, ok := cyrillicToLatin[r]; ok {
+			latin.WriteString(value)
+		} else {
+			latin.WriteRune(r)
+		}
+	}
+	variants := []string{text}
+	for _, variant := range []string{latin.String(), latinToCyrillic.Replace(text)} {
+		if !slices.Contains(variants, variant) {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// Слова строки в нижнем регистре
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Множество триграмм строки по правилам pg_trgm: каждое слово дополняется двумя пробелами слева и одним справа
+func trigrams(text string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, word := range words(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+/*
+Сходство строк по триграммам (аналог similarity из pg_trgm), от 0 до 1
+*/
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	common := 0
+	for trigram := range ta {
+		if _, ok := tb[trigram]; ok {
+			common++
+		}
+	}
+	union := len(ta) + len(tb) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+/*
+Релевантность записи: наибольшее сходство имени, фамилии или полного имени
+с одним из вариантов написания плюс вес совпадения всех слов запроса.
+Возвращает false, если запись не подходит под запрос
+*/
+func Score(person models.Person, variants []string) (float64, bool) {
+	fullName := person.Name + " " + person.Surname
+	best := 0.0
+	for _, variant := range variants {
+		for _, value := range []string{person.Name, person.Surname, fullName} {
+			if similarity := Similarity(value, variant); similarity > best {
+				best = similarity
+			}
+		}
+	}
+	tokens := map[string]bool{}
+	for _, value := range []string{person.Name, person.Surname, person.Email, person.Telephone} {
+		for _, word := range words(value) {
+			tokens[word] = true
+		}
+	}
+	query := words(variants[0])
+	matched := len(query) > 0
+	for _, word := range query {
+		matched = matched && tokens[word]
+	}
+	if matched {
+		best += fullTextWeight
+	}
+	return best, matched || best >= SimilarityThreshold
+}
+
+/*
+Ранжированный поиск среди записей в приложении (для хранилищ без PostgreSQL):
+записи упорядочены по убыванию релевантности, затем по id
+*/
+func RankPersons(persons []models.Person, text string, page PageRequest) (Page, error) {
+	var result Page
+	if err := ValidateSearchText(text); err != nil {
+		return result, err
+	}
+	offset, err := page.Offset()
+	if err != nil {
+		return result, err
+	}
+	variants := SpellingVariants(text)
+	type scored struct {
+		person models.Person
+		score  float64
+	}
+	var matches []scored
+	for _, person := range persons {
+		if score, ok := Score(person, variants); ok {
+			matches = append(matches, scored{person, score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].person.ID < matches[j].person.ID
+	})
+	result.TotalCount = int64(len(matches))
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	end := offset + page.Limit()
+	if end < len(matches) {
+		result.NextPageToken = NewOffsetToken(end)
+	} else {
+		end = len(matches)
+	}
+	for _, match := range matches[offset:end] {
+		result.Persons = append(result.Persons, match.person)
+		result.Scores = append(result.Scores, match.score)
+	}
+	return result, nil
+}
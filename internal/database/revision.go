@@ -0,0 +1,148 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAsOf      = errors.New("invalid AsOf: expected revision number or RFC 3339 timestamp")
+	ErrRevisionNotFound = errors.New("revision not found")
+)
+
+/*
+Момент чтения записи: номер ревизии (версия записи) или время
+*/
+type AsOf struct {
+	Revision uint
+	Time     time.Time
+}
+
+/*
+Функция разбора AsOf: положительное число - номер ревизии, иначе время в формате RFC 3339
+*/
+func ParseAsOf(value string) (AsOf, error) {
+	value = strings.TrimSpace(value)
+	if revision, err := strconv.ParseUint(value, 10, 32); err == nil {
+		if revision == 0 {
+			return AsOf{}, ErrInvalidAsOf
+		}
+		return AsOf{Revision: uint(revision)}, nil
+	}
+	moment, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return AsOf{}, ErrInvalidAsOf
+	}
+	return AsOf{Time: moment}, nil
+}
+
+// Применение изменений полей из журнала к состоянию записи
+func applyChanges(person *models.Person, changes []models.FieldChange) {
+	for _, change := range changes {
+		switch change.Field {
+		case "name":
+			person.Name = change.After
+		case "surname":
+			person.Surname = change.After
+		case "age":
+			person.Age, _ = strconv.Atoi(change.After)
+		case "email":
+			person.Email = change.After
+		case "telephone":
+			person.Telephone = change.After
+		}
+	}
+}
+
+/*
+Функция восстановления состояния записи по журналу изменений (в порядке операций).
+Для номера ревизии возвращается состояние после операции, создавшей эту версию
+(ErrRevisionNotFound, если такой нет), для времени - состояние на этот момент
+(ErrPersonNotFound, если запись еще не добавлена или уже удалена).
+Окончательно удаленная запись не читается ни в одном состоянии (ErrPersonNotFound).
+Если журнал начинается не с добавления записи, прежние значения полей неизвестны,
+и состояние не восстанавливается (ErrRevisionNotFound)
+*/
+func PersonAt(history []models.AuditEntry, asOf AsOf) (*models.Person, error) {
+	for _, entry := range history {
+		if entry.Operation == models.AuditPurge {
+			return nil, ErrPersonNotFound
+		}
+	}
+	if len(history) > 0 && history[0].Operation != models.AuditAdd {
+		return nil, ErrRevisionNotFound
+	}
+	var (
+		state    *models.Person
+		deleted  bool
+		revision *models.Person
+	)
+	for _, entry := range history {
+		if asOf.Revision == 0 && entry.CreatedAt.After(asOf.Time) {
+			break
+		}
+		if entry.Operation == models.AuditAdd {
+			state = &models.Person{ID: entry.PersonID}
+		}
+		version := state.Version
+		applyChanges(state, entry.Changes)
+		switch entry.Operation {
+		case models.AuditAdd, models.AuditUpdate, models.AuditRestore:
+			deleted = false
+			version++
+		case models.AuditDelete:
+			deleted = true
+		}
+		//Записи журнала, сделанные до сохранения версии, содержат 0
+		if entry.Version != 0 {
+			version = entry.Version
+		}
+		state.Version = version
+		if asOf.Revision != 0 && state.Version == asOf.Revision && !deleted {
+			snapshot := *state
+			revision = &snapshot
+		}
+	}
+	if asOf.Revision != 0 {
+		if revision == nil {
+			return nil, ErrRevisionNotFound
+		}
+		return revision, nil
+	}
+	if state == nil || deleted {
+		return nil, ErrPersonNotFound
+	}
+	return state, nil
+}
+
+/*
+Функция возврата записи к ревизии revision: значения полей ревизии сохраняются
+как новое обновление записи с текущей версией version. check проверяет значения
+ревизии перед сохранением; его ошибка возвращается без изменения записи
+*/
+func RevertPerson(repo PersonRepository, id uint, revision uint, version uint, check func(person *models.Person) error) (*models.Person, error) {
+	var person *models.Person
+	err := repo.Transaction(func(tx PersonRepository) error {
+		history, err := tx.PersonHistory(id)
+		if err != nil {
+			return err
+		}
+		target, err := PersonAt(history, AsOf{Revision: revision})
+		if err != nil {
+			return err
+		}
+		if err := check(target); err != nil {
+			return err
+		}
+		target.Version = version
+		if err := tx.UpdatePerson(target); err != nil {
+			return err
+		}
+		person = target
+		return nil
+	})
+	return person, err
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func auditEntry(operation string, version uint, minute int, changes ...models.FieldChange) models.AuditEntry {
+	return models.AuditEntry{
+		PersonID:  1,
+		Operation: operation,
+		Version:   version,
+		Changes:   changes,
+		CreatedAt: time.Date(2026, 1, 1, 0, minute, 0, 0, time.UTC),
+	}
+}
+
+func TestPersonAt(t *testing.T) {
+	history := []models.AuditEntry{
+		auditEntry(models.AuditAdd, 1, 0, models.FieldChange{Field: "name", After: "Иван"}, models.FieldChange{Field: "age", After: "30"}),
+		auditEntry(models.AuditUpdate, 2, 10, models.FieldChange{Field: "name", Before: "Иван", After: "Петр"}),
+		auditEntry(models.AuditDelete, 2, 20),
+	}
+	person, err := PersonAt(history, AsOf{Revision: 1})
+	if err != nil || person.Name != "Иван" || person.Age != 30 || person.Version != 1 {
+		t.Errorf("revision 1: got %+v, %v", person, err)
+	}
+	person, err = PersonAt(history, AsOf{Time: history[1].CreatedAt.Add(time.Minute)})
+	if err != nil || person.Name != "Петр" || person.Version != 2 {
+		t.Errorf("as of time: got %+v, %v", person, err)
+	}
+	if _, err := PersonAt(history, AsOf{Time: history[2].CreatedAt}); !errors.Is(err, ErrPersonNotFound) {
+		t.Errorf("as of deletion: got %v, want ErrPersonNotFound", err)
+	}
+	if _, err := PersonAt(history, AsOf{Revision: 3}); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("missing revision: got %v, want ErrRevisionNotFound", err)
+	}
+}
+
+func TestPersonAtPurged(t *testing.T) {
+	history := []models.AuditEntry{
+		auditEntry(models.AuditAdd, 1, 0, models.FieldChange{Field: "name", After: "Иван"}),
+		auditEntry(models.AuditDelete, 1, 10),
+		auditEntry(models.AuditPurge, 1, 20, models.FieldChange{Field: "name", Before: "Иван"}),
+	}
+	if _, err := PersonAt(history, AsOf{Revision: 1}); !errors.Is(err, ErrPersonNotFound) {
+		t.Errorf("revision of purged person: got %v, want ErrPersonNotFound", err)
+	}
+	if _, err := PersonAt(history, AsOf{Time: history[0].CreatedAt}); !errors.Is(err, ErrPersonNotFound) {
+		t.Errorf("state of purged person: got %v, want ErrPersonNotFound", err)
+	}
+}
+
+func TestPersonAtWithoutBaseline(t *testing.T) {
+	//Запись добавлена до ведения журнала и изменена после: исходные значения полей неизвестны
+	history := []models.AuditEntry{
+		auditEntry(models.AuditUpdate, 2, 0, models.FieldChange{Field: "name", Before: "Иван", After: "Петр"}),
+	}
+	if _, err := PersonAt(history, AsOf{Revision: 2}); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("revision without baseline: got %v, want ErrRevisionNotFound", err)
+	}
+	if _, err := PersonAt(history, AsOf{Time: history[0].CreatedAt}); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("state without baseline: got %v, want ErrRevisionNotFound", err)
+	}
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Максимальная длина значения условия поиска
+const MaxQueryLength = 200
+
+// Операции сравнения строковых полей
+const (
+	OpEquals   = "equals"
+	OpPrefix   = "prefix"
+	OpContains = "contains"
+)
+
+/*
+Строковые поля, доступные для поиска, и соответствующие им столбцы таблицы people
+*/
+var SearchColumns = map[string]string{
+	"name":      "name",
+	"surname":   "surname",
+	"email":     "email",
+	"telephone": "telephone",
+}
+
+/*
+Условие по строковому полю
+*/
+type TextCondition struct {
+	Field      string
+	Op         string
+	Value      string
+	IgnoreCase bool
+}
+
+/*
+Запрос поиска: условия по строковым полям и диапазон возраста.
+Условия объединяются через AND, при Any - через OR; диапазон возраста - одно условие
+*/
+type SearchQuery struct {
+	Any    bool
+	Text   []TextCondition
+	MinAge *int
+	MaxAge *int
+}
+
+/*
+Функция преобразования свободной строки поиска: число - точное совпадение возраста,
+иначе вхождение подстроки в любое строковое поле с учетом регистра
+*/
+func FreeTextQuery(searchString string) SearchQuery {
+	searchString = strings.TrimSpace(searchString)
+	if age, err := strconv.Atoi(searchString); err == nil {
+		return SearchQuery{MinAge: &age, MaxAge: &age}
+	}
+	if searchString == "" {
+		return SearchQuery{}
+	}
+	query := SearchQuery{Any: true}
+	for _, field := range []string{"name", "surname", "email", "telephone"} {
+		query.Text = append(query.Text, TextCondition{Field: field, Op: OpContains, Value: searchString})
+	}
+	return query
+}
+
+// Есть ли в запросе условие по возрасту
+func (q SearchQuery) HasAge() bool {
+	return q.MinAge != nil || q.MaxAge != nil
+}
+
+/*
+Проверка запроса: ErrEmptyQuery без условий или с пустым значением,
+ErrQueryTooLong для слишком длинного значения, ErrInvalidInput для неизвестного поля,
+операции или пустого диапазона возраста
+*/
+func (q SearchQuery) Validate() error {
+	if len(q.Text) == 0 && !q.HasAge() {
+		return ErrEmptyQuery
+	}
+	for _, condition := range q.Text {
+		if condition.Value == "" {
+			return ErrEmptyQuery
+		}
+		if utf8.RuneCountInString(condition.Value) > MaxQueryLength {
+			return ErrQueryTooLong
+		}
+		if _, ok := SearchColumns[condition.Field]; !ok {
+			return ErrInvalidInput
+		}
+		switch condition.Op {
+		case OpEquals, OpPrefix, OpContains:
+		default:
+			return ErrInvalidInput
+		}
+	}
+	if q.MinAge != nil && q.MaxAge != nil && *q.MinAge > *q.MaxAge {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+/*
+Проверка записи на соответствие запросу (для хранилищ без SQL)
+*/
+func (q SearchQuery) Match(person models.Person) bool {
+	var results []bool
+	for _, condition := range q.Text {
+		results = append(results, condition.Match(person))
+	}
+	if q.HasAge() {
+		results = append(results, (q.MinAge == nil || person.Age >= *q.MinAge) && (q.MaxAge == nil || person.Age <= *q.MaxAge))
+	}
+	for _, result := range results {
+		if q.Any && result {
+			return true
+		}
+		if !q.Any && !result {
+			return false
+		}
+	}
+	return !q.Any
+}
+
+// Проверка строкового поля записи на соответствие условию
+func (c TextCondition) Match(person models.Person) bool {
+	value, pattern := SortText(person, c.Field), c.Value
+	if c.IgnoreCase {
+		value, pattern = strings.ToLower(value), strings.ToLower(pattern)
+	}
+	switch c.Op {
+	case OpEquals:
+		return value == pattern
+	case OpPrefix:
+		return strings.HasPrefix(value, pattern)
+	}
+	return strings.Contains(value, pattern)
+}
+
+/*
+Шаблон LIKE для условия: спецсимволы %, _ и \ экранируются обратной косой чертой
+*/
+func (c TextCondition) LikePattern() string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(c.Value)
+	switch c.Op {
+	case OpEquals:
+		return escaped
+	case OpPrefix:
+		return escaped + "%"
+	}
+	return "%" + escaped + "%"
+}
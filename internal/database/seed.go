@@ -0,0 +1,136 @@
+package database
+
+import (
+	"WST_lab1_server_new1/internal/logging"
+	"WST_lab1_server_new1/internal/models"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Режимы заполнения хранилища записями из файла конфигурации
+const (
+	SeedNone          = "none"            // не изменять данные
+	SeedIfEmpty       = "if-empty"        // заполнить только пустое хранилище
+	SeedUpsertByEmail = "upsert-by-email" // добавить новые и обновить существующие по email
+	SeedReset         = "reset"           // удалить все записи и заполнить заново
+)
+
+/*
+Итог заполнения хранилища
+*/
+type SeedResult struct {
+	Deleted  int64
+	Inserted int
+	Updated  int
+	Skipped  int
+}
+
+/*
+Функция заполнения хранилища записями persons в выбранном режиме с выводом итога в лог
+*/
+func Seed(repo PersonRepository, mode string, persons []models.Person) (SeedResult, error) {
+	result, err := seed(repo, mode, persons)
+	if err != nil {
+		logging.Logger.Error("Seeding failed", zap.String("mode", mode), zap.Error(err))
+		return result, err
+	}
+	logging.Logger.Info("Seeding completed",
+		zap.String("mode", mode),
+		zap.Int64("deleted", result.Deleted),
+		zap.Int("inserted", result.Inserted),
+		zap.Int("updated", result.Updated),
+		zap.Int("skipped", result.Skipped))
+	return result, nil
+}
+
+func seed(repo PersonRepository, mode string, persons []models.Person) (SeedResult, error) {
+	var result SeedResult
+	switch mode {
+	case "", SeedNone:
+		result.Skipped = len(persons)
+		return result, nil
+	case SeedIfEmpty:
+		existing, err := repo.GetAllPersons(PageRequest{Size: 1})
+		if err != nil {
+			return result, err
+		}
+		if existing.TotalCount > 0 {
+			result.Skipped = len(persons)
+			return result, nil
+		}
+	case SeedReset:
+		deleted, err := repo.DeleteAllPersons()
+		if err != nil {
+			return result, err
+		}
+		result.Deleted = deleted
+	case SeedUpsertByEmail:
+		for _, person := range persons {
+			existing, err := repo.CheckPersonByEmail(person.Email, 0)
+			if err != nil && !errors.Is(err, ErrPersonNotFound) {
+				return result, err
+			}
+			if existing == nil {
+				if _, err := repo.AddPerson(&person); err != nil {
+					return result, err
+				}
+				result.Inserted++
+				continue
+			}
+			person.ID, person.Version = existing.ID, existing.Version
+			if person == *existing {
+				result.Skipped++
+				continue
+			}
+			if err := repo.UpdatePerson(&person); err != nil {
+				return result, err
+			}
+			result.Updated++
+		}
+		return result, nil
+	default:
+		return result, fmt.Errorf("unknown seed mode %q", mode)
+	}
+	//Режимы if-empty (для пустого хранилища) и reset добавляют все записи
+	for _, person := range persons {
+		if _, err := repo.AddPerson(&person); err != nil {
+			return result, err
+		}
+		result.Inserted++
+	}
+	return result, nil
+}
+
+/*
+Функция создания пользователей из файла конфигурации. Существующие пользователи
+не изменяются, чтобы не отменять смену пароля или блокировку командой user
+*/
+func SeedUsers(repo UserRepository, users []models.User) error {
+	created := 0
+	for _, user := range users {
+		if user.Username == "" || user.PasswordHash == "" {
+			return fmt.Errorf("user %q: username and passwordHash are required", user.Username)
+		}
+		if user.Role == "" {
+			user.Role = models.RoleReader
+		}
+		if !models.ValidRole(user.Role) {
+			return fmt.Errorf("user %q: unknown role %q", user.Username, user.Role)
+		}
+		_, err := repo.GetUser(user.Username)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrUserNotFound) {
+			return err
+		}
+		if _, err := repo.AddUser(&user); err != nil {
+			return err
+		}
+		created++
+	}
+	logging.Logger.Info("Users seeded", zap.Int("created", created), zap.Int("configured", len(users)))
+	return nil
+}
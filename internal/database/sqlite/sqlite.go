@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/database/gormdb"
+	"WST_lab1_server_new1/internal/logging"
+
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"strings"
+
+	sqlitedriver "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Файл базы данных по умолчанию
+const defaultPath = "wst.db"
+
+/*
+Встроенная функция lower в SQLite изменяет регистр только латиницы.
+Заменяем ее на Unicode-версию, чтобы поиск без учета регистра работал
+для кириллицы так же, как в PostgreSQL
+*/
+func init() {
+	sqlitedriver.MustRegisterDeterministicScalarFunction("lower", 1, func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch value := args[0].(type) {
+		case string:
+			return strings.ToLower(value), nil
+		case []byte:
+			return strings.ToLower(string(value)), nil
+		}
+		return args[0], nil
+	})
+}
+
+/*
+Инициализация встроенной базы данных SQLite (драйвер на чистом Go, без cgo)
+*/
+func Init() (*database.Storage, error) {
+	conn, err := Open()
+	if err != nil {
+		log.Fatalf("error opening database: %v", err)
+		return nil, err
+	}
+	return gormdb.NewStorage(conn)
+}
+
+/*
+Открытие базы данных без миграций и заполнения (используется командой migrate).
+case_sensitive_like включает регистрозависимый LIKE, как в PostgreSQL,
+чтобы SearchPerson находил те же записи на обоих драйверах.
+_txlock=immediate берет блокировку записи в начале транзакции: иначе транзакция,
+начатая чтением, не может перейти к записи при конкурентной записи, и SQLite
+сразу возвращает SQLITE_BUSY без ожидания busy_timeout
+*/
+func Open() (*gorm.DB, error) {
+	path := config.DatabaseSetting.Path
+	if path == "" {
+		path = defaultPath
+	}
+	dsn := "file:" + path +
+		"?_pragma=foreign_keys(1)" +
+		"&_pragma=busy_timeout(5000)" +
+		"&_pragma=journal_mode(WAL)" +
+		"&_pragma=case_sensitive_like(1)" +
+		"&_txlock=immediate"
+	//Открываем базу данных
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormdb.Logger(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+	logging.Logger.Info("SQLite database opened successfully.", zap.String("path", path))
+	return conn, nil
+}
@@ -0,0 +1,599 @@
+package database_test
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/database/gormdb"
+	"WST_lab1_server_new1/internal/database/memory"
+	"WST_lab1_server_new1/internal/database/sqlite"
+	"WST_lab1_server_new1/internal/logging"
+	"WST_lab1_server_new1/internal/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	//Без вывода SQL запросов gorm
+	config.GeneralServerSetting.LogLevel = "fatal"
+	os.Exit(m.Run())
+}
+
+/*
+Новое хранилище SQLite во временном каталоге теста (с миграциями)
+*/
+func newSQLiteStorage(t *testing.T) *database.Storage {
+	t.Helper()
+	config.DatabaseSetting.Path = filepath.Join(t.TempDir(), "test.db")
+	db, err := sqlite.Open()
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	storage, err := gormdb.NewStorage(db)
+	if err != nil {
+		t.Fatalf("init sqlite storage: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return storage
+}
+
+/*
+Выполнение теста на каждой реализации хранилища: в памяти и SQLite
+*/
+func forEachStorage(t *testing.T, test func(t *testing.T, repo database.PersonRepository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, memory.NewPersonRepository().WithActor(database.Actor{Username: "tester"}))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, newSQLiteStorage(t).PersonRepository.WithActor(database.Actor{Username: "tester"}))
+	})
+}
+
+func newPerson(n int) *models.Person {
+	return &models.Person{
+		Name:      fmt.Sprintf("Name%d", n),
+		Surname:   "Surname",
+		Age:       20 + n,
+		Email:     fmt.Sprintf("person%d@mail.ru", n),
+		Telephone: fmt.Sprintf("+7900123%04d", n),
+	}
+}
+
+func mustAdd(t *testing.T, repo database.PersonRepository, person *models.Person) uint {
+	t.Helper()
+	id, err := repo.AddPerson(person)
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+	return id
+}
+
+func TestAddAndGetPerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		person, err := repo.GetPerson(id)
+		if err != nil {
+			t.Fatalf("GetPerson: %v", err)
+		}
+		if person.Email != "person1@mail.ru" || person.Age != 21 || person.Version != 1 {
+			t.Errorf("unexpected person %+v", person)
+		}
+		if _, err := repo.GetPerson(id + 100); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("GetPerson of missing id: got %v, want ErrPersonNotFound", err)
+		}
+	})
+}
+
+func TestAddPersonDuplicateEmail(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		mustAdd(t, repo, newPerson(1))
+		duplicate := newPerson(2)
+		duplicate.Email = "person1@mail.ru"
+		if _, err := repo.AddPerson(duplicate); !errors.Is(err, database.ErrEmailExists) {
+			t.Errorf("got %v, want ErrEmailExists", err)
+		}
+	})
+}
+
+func TestUpdatePerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		mustAdd(t, repo, newPerson(2))
+
+		person, _ := repo.GetPerson(id)
+		person.Surname = "Changed"
+		if err := repo.UpdatePerson(person); err != nil {
+			t.Fatalf("UpdatePerson: %v", err)
+		}
+		if person.Version != 2 {
+			t.Errorf("version after update = %d, want 2", person.Version)
+		}
+		stored, _ := repo.GetPerson(id)
+		if stored.Surname != "Changed" || stored.Version != 2 {
+			t.Errorf("unexpected stored person %+v", stored)
+		}
+
+		stale := *stored
+		stale.Version = 1
+		if err := repo.UpdatePerson(&stale); !errors.Is(err, database.ErrVersionConflict) {
+			t.Errorf("stale update: got %v, want ErrVersionConflict", err)
+		}
+		taken := *stored
+		taken.Email = "person2@mail.ru"
+		if err := repo.UpdatePerson(&taken); !errors.Is(err, database.ErrEmailExists) {
+			t.Errorf("update to taken email: got %v, want ErrEmailExists", err)
+		}
+		missing := newPerson(3)
+		missing.ID, missing.Version = id+100, 1
+		if err := repo.UpdatePerson(missing); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("update of missing id: got %v, want ErrPersonNotFound", err)
+		}
+	})
+}
+
+func TestDeleteAndRestorePerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		if err := repo.DeletePerson(id); err != nil {
+			t.Fatalf("DeletePerson: %v", err)
+		}
+		if _, err := repo.GetPerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("deleted person is visible: %v", err)
+		}
+		if err := repo.DeletePerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("second delete: got %v, want ErrPersonNotFound", err)
+		}
+		//Email удаленной записи свободен, поэтому восстановление невозможно
+		mustAdd(t, repo, newPerson(1))
+		if _, err := repo.RestorePerson(id); !errors.Is(err, database.ErrEmailExists) {
+			t.Errorf("restore with taken email: got %v, want ErrEmailExists", err)
+		}
+	})
+}
+
+func TestRestorePerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		if _, err := repo.RestorePerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("restore of live person: got %v, want ErrPersonNotFound", err)
+		}
+		if err := repo.DeletePerson(id); err != nil {
+			t.Fatalf("DeletePerson: %v", err)
+		}
+		restored, err := repo.RestorePerson(id)
+		if err != nil {
+			t.Fatalf("RestorePerson: %v", err)
+		}
+		if restored.Version != 2 || restored.DeletedAt != nil || restored.DeletedBy != "" {
+			t.Errorf("unexpected restored person %+v", restored)
+		}
+		person, err := repo.GetPerson(id)
+		if err != nil {
+			t.Fatalf("restored person is not visible: %v", err)
+		}
+		if person.Version != 2 || person.Email != "person1@mail.ru" {
+			t.Errorf("unexpected person %+v", person)
+		}
+		history, err := repo.PersonHistory(id)
+		if err != nil {
+			t.Fatalf("PersonHistory: %v", err)
+		}
+		if len(history) != 3 || history[2].Operation != models.AuditRestore || history[2].Version != 2 {
+			t.Errorf("restore is not recorded in history: %+v", history)
+		}
+	})
+}
+
+func TestPurgePerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		if err := repo.PurgePerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("purge of live person: got %v, want ErrPersonNotFound", err)
+		}
+		if err := repo.DeletePerson(id); err != nil {
+			t.Fatalf("DeletePerson: %v", err)
+		}
+		if err := repo.PurgePerson(id); err != nil {
+			t.Fatalf("PurgePerson: %v", err)
+		}
+		if _, err := repo.RestorePerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("restore of purged person: got %v, want ErrPersonNotFound", err)
+		}
+		if err := repo.PurgePerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("second purge: got %v, want ErrPersonNotFound", err)
+		}
+		//Журнал очищенной записи сохраняется вместе с ее последним состоянием
+		history, err := repo.PersonHistory(id)
+		if err != nil {
+			t.Fatalf("PersonHistory: %v", err)
+		}
+		if len(history) != 3 || history[2].Operation != models.AuditPurge || len(history[2].Changes) == 0 {
+			t.Errorf("purge is not recorded in history: %+v", history)
+		}
+	})
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		for i := 1; i <= 4; i++ {
+			mustAdd(t, repo, newPerson(i))
+		}
+		for _, id := range []uint{1, 2, 3} {
+			if err := repo.DeletePerson(id); err != nil {
+				t.Fatalf("DeletePerson: %v", err)
+			}
+		}
+		//Записи, удаленные позже before, не затрагиваются
+		purged, err := repo.PurgeDeleted(time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeDeleted: %v", err)
+		}
+		if purged != 0 {
+			t.Errorf("purged = %d, want 0", purged)
+		}
+		if _, err := repo.RestorePerson(3); err != nil {
+			t.Fatalf("RestorePerson: %v", err)
+		}
+		purged, err = repo.PurgeDeleted(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatalf("PurgeDeleted: %v", err)
+		}
+		if purged != 2 {
+			t.Errorf("purged = %d, want 2", purged)
+		}
+		for _, id := range []uint{1, 2} {
+			if _, err := repo.RestorePerson(id); !errors.Is(err, database.ErrPersonNotFound) {
+				t.Errorf("person %d is not purged: %v", id, err)
+			}
+		}
+		for _, id := range []uint{3, 4} {
+			if _, err := repo.GetPerson(id); err != nil {
+				t.Errorf("person %d is purged: %v", id, err)
+			}
+		}
+	})
+}
+
+func TestGetAllPersonsPaging(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		for i := 1; i <= 5; i++ {
+			mustAdd(t, repo, newPerson(i))
+		}
+		var ids []uint
+		page := database.PageRequest{Size: 2, SortBy: "age", Descending: true}
+		for {
+			result, err := repo.GetAllPersons(page)
+			if err != nil {
+				t.Fatalf("GetAllPersons: %v", err)
+			}
+			if result.TotalCount != 5 {
+				t.Errorf("TotalCount = %d, want 5", result.TotalCount)
+			}
+			for _, person := range result.Persons {
+				ids = append(ids, person.ID)
+			}
+			if result.NextPageToken == "" {
+				break
+			}
+			page.Token = result.NextPageToken
+		}
+		if fmt.Sprint(ids) != "[5 4 3 2 1]" {
+			t.Errorf("ids = %v, want [5 4 3 2 1]", ids)
+		}
+	})
+}
+
+// Идентификаторы всех записей при постраничном обходе GetAllPersons
+func pagedIDs(t *testing.T, repo database.PersonRepository, page database.PageRequest) string {
+	t.Helper()
+	var ids []uint
+	for {
+		result, err := repo.GetAllPersons(page)
+		if err != nil {
+			t.Fatalf("GetAllPersons: %v", err)
+		}
+		for _, person := range result.Persons {
+			ids = append(ids, person.ID)
+		}
+		if result.NextPageToken == "" {
+			return fmt.Sprint(ids)
+		}
+		page.Token = result.NextPageToken
+	}
+}
+
+func TestPagingWithNullSortValues(t *testing.T) {
+	repo := newSQLiteStorage(t).PersonRepository
+	for i := 1; i <= 4; i++ {
+		mustAdd(t, repo, newPerson(i))
+	}
+	//Записи, созданные вне сервиса, могут не иметь имени и возраста
+	db := repo.(*gormdb.PersonRepository).DB
+	if err := db.Exec("UPDATE people SET name = NULL, age = NULL WHERE id IN (2, 3)").Error; err != nil {
+		t.Fatal(err)
+	}
+	//NULL упорядочивается как пустое значение, записи не теряются между страницами
+	if ids := pagedIDs(t, repo, database.PageRequest{Size: 1, SortBy: "name"}); ids != "[2 3 1 4]" {
+		t.Errorf("sort by name: ids = %v, want [2 3 1 4]", ids)
+	}
+	if ids := pagedIDs(t, repo, database.PageRequest{Size: 1, SortBy: "name", Descending: true}); ids != "[4 1 3 2]" {
+		t.Errorf("sort by name descending: ids = %v, want [4 1 3 2]", ids)
+	}
+	if ids := pagedIDs(t, repo, database.PageRequest{Size: 1, SortBy: "age", Descending: true}); ids != "[4 1 3 2]" {
+		t.Errorf("sort by age descending: ids = %v, want [4 1 3 2]", ids)
+	}
+}
+
+func TestSearchPerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		mustAdd(t, repo, &models.Person{Name: "Иван", Surname: "Петров", Age: 30, Email: "ivan@mail.ru", Telephone: "+79001234567"})
+		mustAdd(t, repo, &models.Person{Name: "Петр", Surname: "Иванов", Age: 40, Email: "petr@mail.ru", Telephone: "+79001234568"})
+		minAge := 35
+		query := database.SearchQuery{
+			Text:   []database.TextCondition{{Field: "surname", Op: database.OpContains, Value: "иван", IgnoreCase: true}},
+			MinAge: &minAge,
+		}
+		result, err := repo.SearchPerson(query, database.PageRequest{})
+		if err != nil {
+			t.Fatalf("SearchPerson: %v", err)
+		}
+		if len(result.Persons) != 1 || result.Persons[0].Name != "Петр" {
+			t.Errorf("unexpected result %+v", result.Persons)
+		}
+	})
+}
+
+func TestRankedSearch(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		mustAdd(t, repo, &models.Person{Name: "Иван", Surname: "Петров", Age: 30, Email: "ivan@mail.ru", Telephone: "+79001234567"})
+		mustAdd(t, repo, &models.Person{Name: "Петр", Surname: "Иванов", Age: 40, Email: "petr@mail.ru", Telephone: "+79001234568"})
+		mustAdd(t, repo, &models.Person{Name: "Сергей", Surname: "Сидоров", Age: 50, Email: "sergey@mail.ru", Telephone: "+79001234569"})
+		mustAdd(t, repo, &models.Person{Name: "Ivan", Surname: "Ivanov", Age: 60, Email: "ivanov@mail.ru", Telephone: "+79001234570"})
+		deleted := mustAdd(t, repo, &models.Person{Name: "Олег", Surname: "Иванов", Age: 70, Email: "oleg@mail.ru", Telephone: "+79001234571"})
+		if err := repo.DeletePerson(deleted); err != nil {
+			t.Fatalf("DeletePerson: %v", err)
+		}
+
+		//Точное совпадение фамилии, затем транслитерация, затем похожее имя; удаленные записи не находятся
+		var ids []uint
+		var scores []float64
+		page := database.PageRequest{Size: 2}
+		for {
+			result, err := repo.RankedSearch("Иванов", page)
+			if err != nil {
+				t.Fatalf("RankedSearch: %v", err)
+			}
+			if result.TotalCount != 3 {
+				t.Errorf("TotalCount = %d, want 3", result.TotalCount)
+			}
+			if len(result.Scores) != len(result.Persons) {
+				t.Fatalf("%d scores for %d persons", len(result.Scores), len(result.Persons))
+			}
+			for i, person := range result.Persons {
+				ids = append(ids, person.ID)
+				scores = append(scores, result.Scores[i])
+			}
+			if result.NextPageToken == "" {
+				break
+			}
+			page.Token = result.NextPageToken
+		}
+		if fmt.Sprint(ids) != "[2 4 1]" {
+			t.Errorf("ids = %v, want [2 4 1]", ids)
+		}
+		for i := 1; i < len(scores); i++ {
+			if scores[i] > scores[i-1] {
+				t.Errorf("scores are not descending: %v", scores)
+			}
+		}
+
+		result, err := repo.RankedSearch("Сидоров Сергей", database.PageRequest{})
+		if err != nil {
+			t.Fatalf("RankedSearch: %v", err)
+		}
+		if len(result.Persons) != 1 || result.Persons[0].ID != 3 {
+			t.Errorf("unexpected result %+v", result.Persons)
+		}
+
+		if _, err := repo.RankedSearch("  ", database.PageRequest{}); !errors.Is(err, database.ErrEmptyQuery) {
+			t.Errorf("empty query: got %v, want %v", err, database.ErrEmptyQuery)
+		}
+	})
+}
+
+func TestTransactionRollback(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		failure := errors.New("rollback")
+		err := repo.Transaction(func(tx database.PersonRepository) error {
+			mustAdd(t, tx, newPerson(1))
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Transaction: got %v, want %v", err, failure)
+		}
+		if _, err := repo.CheckPersonByEmail("person1@mail.ru", 0); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("record of rolled back transaction exists: %v", err)
+		}
+		history, _ := repo.PersonHistory(1)
+		if len(history) != 0 {
+			t.Errorf("audit entries of rolled back transaction: %+v", history)
+		}
+	})
+}
+
+// Число одновременных запросов в тестах конкурентного доступа
+const concurrentRequests = 30
+
+/*
+Одновременный запуск n вызовов call и сбор их ошибок
+*/
+func runConcurrently(n int, call func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = call(i)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestConcurrentAddPerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		errs := runConcurrently(concurrentRequests, func(i int) error {
+			_, err := repo.AddPerson(newPerson(i))
+			return err
+		})
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("AddPerson %d: %v", i, err)
+			}
+		}
+		page, err := repo.GetAllPersons(database.PageRequest{Size: concurrentRequests + 1})
+		if err != nil {
+			t.Fatalf("GetAllPersons: %v", err)
+		}
+		if page.TotalCount != concurrentRequests {
+			t.Errorf("TotalCount = %d, want %d", page.TotalCount, concurrentRequests)
+		}
+	})
+}
+
+func TestConcurrentAddPersonSameEmail(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		errs := runConcurrently(concurrentRequests, func(i int) error {
+			person := newPerson(i)
+			person.Email = "same@mail.ru"
+			_, err := repo.AddPerson(person)
+			return err
+		})
+		created := 0
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, database.ErrEmailExists):
+				t.Errorf("AddPerson %d: got %v, want ErrEmailExists", i, err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("created %d persons with the same email, want 1", created)
+		}
+	})
+}
+
+func TestConcurrentUpdatePerson(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		errs := runConcurrently(concurrentRequests, func(i int) error {
+			person := newPerson(1)
+			person.ID, person.Version = id, 1
+			person.Surname = fmt.Sprintf("Surname%d", i)
+			return repo.UpdatePerson(person)
+		})
+		updated := 0
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				updated++
+			case !errors.Is(err, database.ErrVersionConflict):
+				t.Errorf("UpdatePerson %d: got %v, want ErrVersionConflict", i, err)
+			}
+		}
+		if updated != 1 {
+			t.Errorf("%d updates of version 1 succeeded, want 1", updated)
+		}
+		stored, err := repo.GetPerson(id)
+		if err != nil {
+			t.Fatalf("GetPerson: %v", err)
+		}
+		if stored.Version != 2 {
+			t.Errorf("version = %d, want 2", stored.Version)
+		}
+	})
+}
+
+func TestSeedResetRecordsPurge(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		result, err := database.Seed(repo, database.SeedReset, []models.Person{*newPerson(2)})
+		if err != nil {
+			t.Fatalf("Seed: %v", err)
+		}
+		if result.Deleted != 1 || result.Inserted != 1 {
+			t.Errorf("unexpected seed result %+v", result)
+		}
+		history, err := repo.PersonHistory(id)
+		if err != nil {
+			t.Fatalf("PersonHistory: %v", err)
+		}
+		if len(history) != 2 || history[1].Operation != models.AuditPurge || history[1].Version != 1 {
+			t.Errorf("reset is not recorded in history: %+v", history)
+		}
+	})
+}
+
+func TestUpdatePersonChecksVersionBeforeEmail(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		id := mustAdd(t, repo, newPerson(1))
+		mustAdd(t, repo, newPerson(2))
+		stale := newPerson(1)
+		stale.ID, stale.Version, stale.Email = id, 5, "person2@mail.ru"
+		if err := repo.UpdatePerson(stale); !errors.Is(err, database.ErrVersionConflict) {
+			t.Errorf("stale update to taken email: got %v, want ErrVersionConflict", err)
+		}
+	})
+}
+
+func TestRunBatch(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, repo database.PersonRepository) {
+		failure := errors.New("item failed")
+		//Элемент 1 добавляет запись и завершается ошибкой: его изменения отменяются точкой сохранения
+		apply := func(offset int) func(tx database.PersonRepository, i int) (uint, error) {
+			return func(tx database.PersonRepository, i int) (uint, error) {
+				id, err := tx.AddPerson(newPerson(offset + i))
+				if err == nil && i == 1 {
+					err = failure
+				}
+				return id, err
+			}
+		}
+		results, committed, err := database.RunBatch(repo, true, 3, apply(0))
+		if err != nil || committed {
+			t.Fatalf("atomic batch: committed = %v, err = %v", committed, err)
+		}
+		if !errors.Is(results[1].Err, failure) || results[0].Err != nil || results[2].Err != nil {
+			t.Errorf("atomic batch results: %+v", results)
+		}
+		page, _ := repo.GetAllPersons(database.PageRequest{})
+		if page.TotalCount != 0 {
+			t.Errorf("atomic batch left %d persons", page.TotalCount)
+		}
+
+		results, committed, err = database.RunBatch(repo, false, 3, apply(10))
+		if err != nil || !committed {
+			t.Fatalf("best-effort batch: committed = %v, err = %v", committed, err)
+		}
+		page, _ = repo.GetAllPersons(database.PageRequest{})
+		if page.TotalCount != 2 {
+			t.Errorf("best-effort batch stored %d persons, want 2", page.TotalCount)
+		}
+		if _, err := repo.CheckPersonByEmail("person11@mail.ru", 0); !errors.Is(err, database.ErrPersonNotFound) {
+			t.Errorf("changes of failed item were committed: %v", err)
+		}
+	})
+}
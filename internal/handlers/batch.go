@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+	"WST_lab1_server_new1/internal/validation"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/*
+Проверка параметров пакета: количество элементов и режим (по умолчанию atomic)
+*/
+func batchMode(c *gin.Context, mode string, size int) (atomic bool, ok bool) {
+	if size == 0 || size > database.MaxBatchSize || (mode != "" && mode != models.BatchModeAtomic && mode != models.BatchModeBestEffort) {
+		requestLogger(c).Info("Invalid batch", zap.String("mode", mode), zap.Int("size", size))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidBatchSubcode, models.ErrorInvalidBatchMessage, models.ErrorInvalidBatchCode, models.ErrorInvalidBatchDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+		return false, false
+	}
+	return mode != models.BatchModeBestEffort, true
+}
+
+/*
+Функция выполнения пакета и формирования итога: ошибки элементов передаются
+в результатах с кодами Fault одиночных операций. Ошибка транзакции - Fault Internal
+*/
+func runBatch(c *gin.Context, h *StorageHandler, atomic bool, size int, apply func(tx database.PersonRepository, i int) (uint, error)) (models.BatchSummary, bool) {
+	results, committed, err := database.RunBatch(h.persons(c), atomic, size, apply)
+	if err != nil {
+		requestLogger(c).Error("Error executing batch", zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		return models.BatchSummary{}, false
+	}
+	summary := models.BatchSummary{Committed: committed}
+	for i, result := range results {
+		item := models.BatchItemResult{Index: i, Status: models.BatchStatusOK, ID: result.ID}
+		switch {
+		case result.Err != nil:
+			item = batchItemError(c, i, result.Err)
+			summary.Failed++
+		case !committed:
+			item.Status = models.BatchStatusRolledBack
+		default:
+			summary.Succeeded++
+		}
+		summary.Results = append(summary.Results, item)
+	}
+	requestLogger(c).Info("Batch executed", zap.Bool("committed", committed), zap.Int("succeeded", summary.Succeeded), zap.Int("failed", summary.Failed))
+	return summary, true
+}
+
+// Результат ошибочного элемента пакета с кодами соответствующего Fault
+func batchItemError(c *gin.Context, index int, err error) models.BatchItemResult {
+	item := models.BatchItemResult{Index: index, Status: models.BatchStatusFailed}
+	var invalid *validation.Error
+	switch {
+	case errors.As(err, &invalid):
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorValidationFailedCode, models.ErrorValidationFailedSubcode, models.ErrorValidationFailedDetail
+		item.Violations = invalid.Violations
+	case errors.Is(err, database.ErrEmailExists):
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsSubcode, models.ErrorRecordEmailExistsDetail
+	case errors.Is(err, errInvalidUpdateMode):
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorInvalidUpdateModeCode, models.ErrorInvalidUpdateModeSubcode, models.ErrorInvalidUpdateModeDetail
+	case errors.Is(err, errNoVersion):
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorVersionRequiredCode, models.ErrorVersionRequiredSubcode, models.ErrorVersionRequiredDetail
+	case errors.Is(err, database.ErrVersionConflict):
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorVersionConflictCode, models.ErrorVersionConflictSubcode, models.ErrorVersionConflictDetail
+	case errors.Is(err, database.ErrPersonNotFound):
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundDetail
+	default:
+		requestLogger(c).Error("Error executing batch item", zap.Int("index", index), zap.Error(err))
+		item.ErrorCode, item.Subcode, item.ErrorMessage = models.ErrorInternalCode, models.ErrorInternalSubcode, models.ErrorInternalDetail
+	}
+	return item
+}
+
+// Метод пакетного добавления записей
+func (h *StorageHandler) addPersonsHandler(c *gin.Context, request *models.AddPersonsRequest) {
+	atomic, ok := batchMode(c, request.Mode, len(request.Persons))
+	if !ok {
+		return
+	}
+	summary, ok := runBatch(c, h, atomic, len(request.Persons), func(tx database.PersonRepository, i int) (uint, error) {
+		item := request.Persons[i]
+		person := models.Person{
+			Name:      item.Name,
+			Surname:   item.Surname,
+			Age:       item.Age,
+			Email:     item.Email,
+			Telephone: item.Telephone,
+		}
+		if err := h.Rules.Check(&person); err != nil {
+			return 0, err
+		}
+		return tx.AddPerson(&person)
+	})
+	if ok {
+		writeSOAPResponse(c, http.StatusOK, models.AddPersonsResponse{BatchSummary: summary})
+	}
+}
+
+// Метод пакетного обновления записей
+func (h *StorageHandler) updatePersonsHandler(c *gin.Context, request *models.UpdatePersonsRequest) {
+	atomic, ok := batchMode(c, request.Mode, len(request.Persons))
+	if !ok {
+		return
+	}
+	summary, ok := runBatch(c, h, atomic, len(request.Persons), func(tx database.PersonRepository, i int) (uint, error) {
+		item := request.Persons[i]
+		if err := checkUpdate(h.Rules, &item); err != nil {
+			return 0, err
+		}
+		_, err := updatePerson(tx, &item)
+		return item.ID, err
+	})
+	if ok {
+		writeSOAPResponse(c, http.StatusOK, models.UpdatePersonsResponse{BatchSummary: summary})
+	}
+}
+
+// Метод пакетного удаления записей по ID
+func (h *StorageHandler) deletePersonsHandler(c *gin.Context, request *models.DeletePersonsRequest) {
+	atomic, ok := batchMode(c, request.Mode, len(request.IDs))
+	if !ok {
+		return
+	}
+	summary, ok := runBatch(c, h, atomic, len(request.IDs), func(tx database.PersonRepository, i int) (uint, error) {
+		return request.IDs[i], tx.DeletePerson(request.IDs[i])
+	})
+	if ok {
+		writeSOAPResponse(c, http.StatusOK, models.DeletePersonsResponse{BatchSummary: summary})
+	}
+}
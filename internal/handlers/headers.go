@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ответ возможен только в том же HTTP соединении
+var ErrOnlyAnonymousAddress = errors.New("only anonymous or none reply address is supported")
+
+/*
+Сведения, извлеченные из заголовка запроса обработчиками блоков
+*/
+type RequestHeaders struct {
+	Addressing bool
+	Action     string
+	MessageID  string
+	To         string
+	ReplyTo    string
+	Security   *models.Security
+}
+
+/*
+Обработчик блока заголовка: разбирает блок и заполняет RequestHeaders
+*/
+type HeaderProcessor func(block models.Element, headers *RequestHeaders) error
+
+/*
+Реестр обработчиков блоков заголовка по QName блока
+*/
+type HeaderRegistry struct {
+	processors map[xml.Name]HeaderProcessor
+}
+
+func NewHeaderRegistry() *HeaderRegistry {
+	return &HeaderRegistry{processors: map[xml.Name]HeaderProcessor{}}
+}
+
+// Регистрирует обработчик; повторная регистрация блока - ошибка программы
+func (r *HeaderRegistry) Register(name xml.Name, processor HeaderProcessor) {
+	if _, exists := r.processors[name]; exists {
+		panic(fmt.Sprintf("soap header %s already registered", name.Local))
+	}
+	r.processors[name] = processor
+}
+
+/*
+Обработка заголовка. Сначала проверяются обязательные (mustUnderstand) блоки,
+адресованные сервису: блоки без обработчика возвращаются в notUnderstood и
+запрос не обрабатывается. Затем блоки с обработчиком разбираются; остальные пропускаются
+*/
+func (r *HeaderRegistry) Process(version models.SOAPVersion, header models.Header) (headers RequestHeaders, notUnderstood []xml.Name, err error) {
+	for _, block := range header.Blocks {
+		if _, ok := r.processors[block.Name]; !ok && block.MustUnderstand(version) && block.TargetsReceiver(version) {
+			notUnderstood = append(notUnderstood, block.Name)
+		}
+	}
+	if len(notUnderstood) > 0 {
+		return headers, notUnderstood, nil
+	}
+	for _, block := range header.Blocks {
+		processor, ok := r.processors[block.Name]
+		if !ok || !block.TargetsReceiver(version) {
+			continue
+		}
+		if err := processor(block, &headers); err != nil {
+			return headers, nil, fmt.Errorf("header %s: %w", block.Name.Local, err)
+		}
+	}
+	return headers, nil, nil
+}
+
+/*
+Адрес wsa:ReplyTo или wsa:FaultTo: ответ передается в том же HTTP соединении,
+поэтому допускается только анонимный адрес или адрес none (ответ не нужен)
+*/
+func blockAddress(block models.Element) (string, error) {
+	var reference models.EndpointReference
+	if err := block.Decode(&reference); err != nil {
+		return "", err
+	}
+	address := strings.TrimSpace(reference.Address)
+	if address != models.WSAddressingAnonymous && address != models.WSAddressingNone {
+		return address, ErrOnlyAnonymousAddress
+	}
+	return address, nil
+}
+
+// Текстовое содержимое блока
+func blockText(block models.Element) (string, error) {
+	var value struct {
+		Text string `xml:",chardata"`
+	}
+	if err := block.Decode(&value); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value.Text), nil
+}
+
+/*
+Блоки заголовка, обрабатываемые SOAPHandler
+*/
+var Headers = NewHeaderRegistry()
+
+func init() {
+	//WS-Addressing: действие, идентификатор сообщения, получатель и адреса ответа
+	Headers.Register(xml.Name{Space: models.WSAddressingNamespace, Local: "Action"}, func(block models.Element, headers *RequestHeaders) error {
+		action, err := blockText(block)
+		headers.Addressing, headers.Action = true, action
+		return err
+	})
+	Headers.Register(xml.Name{Space: models.WSAddressingNamespace, Local: "MessageID"}, func(block models.Element, headers *RequestHeaders) error {
+		id, err := blockText(block)
+		headers.Addressing, headers.MessageID = true, id
+		return err
+	})
+	Headers.Register(xml.Name{Space: models.WSAddressingNamespace, Local: "To"}, func(block models.Element, headers *RequestHeaders) error {
+		to, err := blockText(block)
+		headers.Addressing, headers.To = true, to
+		return err
+	})
+	Headers.Register(xml.Name{Space: models.WSAddressingNamespace, Local: "ReplyTo"}, func(block models.Element, headers *RequestHeaders) error {
+		address, err := blockAddress(block)
+		headers.Addressing, headers.ReplyTo = true, address
+		return err
+	})
+	Headers.Register(xml.Name{Space: models.WSAddressingNamespace, Local: "FaultTo"}, func(block models.Element, headers *RequestHeaders) error {
+		_, err := blockAddress(block)
+		headers.Addressing = true
+		return err
+	})
+	//WS-Security: UsernameToken
+	Headers.Register(xml.Name{Space: models.WSSecurityNamespace, Local: "Security"}, func(block models.Element, headers *RequestHeaders) error {
+		headers.Security = &models.Security{}
+		return block.Decode(headers.Security)
+	})
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"WST_lab1_server_new1/internal/auth"
+	"WST_lab1_server_new1/internal/models"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+Описание SOAP операции: QName элемента запроса, действия запроса (SOAPAction / wsa:Action)
+и ответа (wsa:Action ответа), типы запроса/ответа, требуемое разрешение (пусто - без проверки) и обработчик
+*/
+type Operation struct {
+	Name           xml.Name
+	Action         string
+	ResponseAction string
+	Request        any
+	Response       any
+	Permission     auth.Permission
+
+	newRequest        func() any
+	handle            func(h *StorageHandler, c *gin.Context, request any)
+	requestPermission func(request any) auth.Permission
+}
+
+/*
+Функция создания операции в пространстве имен сервиса с типизированным обработчиком
+*/
+func NewOperation[Req any](name string, response any, permission auth.Permission, handle func(h *StorageHandler, c *gin.Context, request *Req)) Operation {
+	return Operation{
+		Name:           xml.Name{Space: models.ServiceNamespace, Local: name},
+		Action:         soapActionURI(name),
+		ResponseAction: soapActionURI(name + "Response"),
+		Request:        new(Req),
+		Response:       response,
+		Permission:     permission,
+		newRequest:     func() any { return new(Req) },
+		handle: func(h *StorageHandler, c *gin.Context, request any) {
+			handle(h, c, request.(*Req))
+		},
+	}
+}
+
+/*
+Функция задания разрешения, зависящего от содержимого запроса (вместо Permission операции)
+*/
+func WithRequestPermission[Req any](op Operation, permission func(request *Req) auth.Permission) Operation {
+	op.requestPermission = func(request any) auth.Permission {
+		return permission(request.(*Req))
+	}
+	return op
+}
+
+// Разрешение, требуемое для выполнения запроса request операции
+func (op *Operation) permission(request any) auth.Permission {
+	if op.requestPermission != nil {
+		return op.requestPermission(request)
+	}
+	return op.Permission
+}
+
+/*
+Реестр операций, по которому SOAPHandler выбирает обработчик, а WSDLHandler строит описание
+*/
+type OperationRegistry struct {
+	operations []*Operation
+	byName     map[xml.Name]*Operation
+	byAction   map[string]*Operation
+}
+
+func NewOperationRegistry() *OperationRegistry {
+	return &OperationRegistry{
+		byName:   map[xml.Name]*Operation{},
+		byAction: map[string]*Operation{},
+	}
+}
+
+// Регистрирует операцию; повторная регистрация имени или действия - ошибка программы
+func (r *OperationRegistry) Register(op Operation) {
+	if _, exists := r.byName[op.Name]; exists {
+		panic(fmt.Sprintf("soap operation %s already registered", op.Name.Local))
+	}
+	if _, exists := r.byAction[op.Action]; exists {
+		panic(fmt.Sprintf("soap action %s already registered", op.Action))
+	}
+	r.operations = append(r.operations, &op)
+	r.byName[op.Name] = &op
+	r.byAction[op.Action] = &op
+}
+
+// Поиск операции по элементу Body. Элементы без пространства имен относятся к пространству сервиса
+func (r *OperationRegistry) Lookup(name xml.Name) (*Operation, bool) {
+	if name.Space == "" {
+		name.Space = models.ServiceNamespace
+	}
+	op, ok := r.byName[name]
+	return op, ok
+}
+
+// Поиск операции по действию: полный URI или имя операции
+func (r *OperationRegistry) LookupAction(action string) (*Operation, bool) {
+	if op, ok := r.byAction[action]; ok {
+		return op, true
+	}
+	op, ok := r.byName[xml.Name{Space: models.ServiceNamespace, Local: strings.TrimPrefix(action, "#")}]
+	return op, ok
+}
+
+// Все операции в порядке регистрации
+func (r *OperationRegistry) All() []*Operation {
+	return r.operations
+}
+
+// Значение SOAPAction для операции
+func soapActionURI(operation string) string {
+	return models.ServiceNamespace + "/" + operation
+}
+
+/*
+Операции, обрабатываемые SOAPHandler, и политика доступа: разрешение, требуемое для каждой операции
+*/
+var Operations = NewOperationRegistry()
+
+func init() {
+	Operations.Register(NewOperation("AddPerson", models.AddPersonResponse{}, auth.PermissionWrite, (*StorageHandler).addPersonHandler))
+	Operations.Register(NewOperation("DeletePerson", models.DeletePersonResponse{}, auth.PermissionDelete, (*StorageHandler).deletePersonHandler))
+	Operations.Register(NewOperation("UpdatePerson", models.UpdatePersonResponse{}, auth.PermissionWrite, (*StorageHandler).updatePersonHandler))
+	//Чтение прежнего состояния записи раскрывает журнал изменений
+	Operations.Register(WithRequestPermission(NewOperation("GetPerson", models.GetPersonResponse{}, auth.PermissionRead, (*StorageHandler).getPersonHandler),
+		func(request *models.GetPersonRequest) auth.Permission {
+			if request.AsOf != "" {
+				return auth.PermissionAudit
+			}
+			return auth.PermissionRead
+		}))
+	Operations.Register(NewOperation("GetAllPersons", models.GetAllPersonsResponse{}, auth.PermissionRead, (*StorageHandler).getAllPersonsHandler))
+	Operations.Register(NewOperation("SearchPerson", models.SearchPersonResponse{}, auth.PermissionRead, (*StorageHandler).searchPersonHandler))
+	Operations.Register(NewOperation("RestorePerson", models.RestorePersonResponse{}, auth.PermissionDelete, (*StorageHandler).restorePersonHandler))
+	Operations.Register(NewOperation("PurgePerson", models.PurgePersonResponse{}, auth.PermissionDelete, (*StorageHandler).purgePersonHandler))
+	Operations.Register(NewOperation("RevertPerson", models.RevertPersonResponse{}, auth.PermissionWrite, (*StorageHandler).revertPersonHandler))
+	Operations.Register(NewOperation("GetPersonHistory", models.GetPersonHistoryResponse{}, auth.PermissionAudit, (*StorageHandler).getPersonHistoryHandler))
+	Operations.Register(NewOperation("AddPersons", models.AddPersonsResponse{}, auth.PermissionWrite, (*StorageHandler).addPersonsHandler))
+	Operations.Register(NewOperation("UpdatePersons", models.UpdatePersonsResponse{}, auth.PermissionWrite, (*StorageHandler).updatePersonsHandler))
+	Operations.Register(NewOperation("DeletePersons", models.DeletePersonsResponse{}, auth.PermissionDelete, (*StorageHandler).deletePersonsHandler))
+}
@@ -1,22 +1,24 @@
 package handlers
 
 import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/auth"
 	"WST_lab1_server_new1/internal/database"
-	"WST_lab1_server_new1/internal/database/postgres"
 	"WST_lab1_server_new1/internal/logging"
+	"WST_lab1_server_new1/internal/middleware"
 	"WST_lab1_server_new1/internal/models"
+	"WST_lab1_server_new1/internal/validation"
 	"bytes"
 	"encoding/base64"
 	"encoding/xml"
 	"errors"
-	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"net/mail"
-	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -24,77 +26,222 @@ import (
 Структура обработчика для разделения логики обработки запросов от доступа к данным
 */
 type StorageHandler struct {
-	Storage *postgres.Storage
+	Storage *database.Storage
+	Tokens  *auth.TokenAuthenticator
+	Rules   *validation.Rules
 }
 
-func createSOAPFault(code string, message string, errorCode string, errorMessage string) models.SOAPFault {
-	fault := models.SOAPFault{}
-	fault.Envelope.Body.Fault.Code = code
-	fault.Envelope.Body.Fault.Message = message
-	fault.Envelope.Body.Fault.Detail.ErrorCode = errorCode
-	fault.Envelope.Body.Fault.Detail.ErrorMessage = errorMessage
+/*
+Функция построения SOAP 1.2 Fault: код Sender/Receiver, необязательный подкод
+из пространства имен сервиса, причина и детали ошибки
+*/
+func newSOAPFault(code string, subcode string, reason string, errorCode string, errorMessage string) models.Fault {
+	fault := models.Fault{
+		Code:   models.FaultCode{Value: code},
+		Reason: models.FaultReason{Text: models.FaultText{Lang: "ru", Value: reason}},
+		Detail: &models.FaultDetail{ErrorCode: errorCode, ErrorMessage: errorMessage},
+	}
+	if subcode != "" {
+		fault.XmlnsTns = models.ServiceNamespace
+		fault.Code.Subcode = &models.FaultCode{Value: "tns:" + subcode}
+	}
 	return fault
 }
 
+// Ключи контекста запроса
+const (
+	soapVersionKey    = "soapVersion"
+	soapActionKey     = "soapAction"
+	userKey           = "user"
+	responseHeaderKey = "responseHeader"
+	addressingKey     = "addressing"
+	responseActionKey = "responseAction"
+	loggerKey         = middleware.LoggerKey
+)
+
+// Логгер запроса: в каждой записи MessageID WS-Addressing, если он передан
+func requestLogger(c *gin.Context) *zap.Logger {
+	if v, ok := c.Get(loggerKey); ok {
+		return v.(*zap.Logger)
+	}
+	return logging.Logger
+}
+
+// Версия SOAP текущего запроса (по умолчанию 1.2)
+func soapVersion(c *gin.Context) models.SOAPVersion {
+	if v, ok := c.Get(soapVersionKey); ok {
+		return v.(models.SOAPVersion)
+	}
+	return models.SOAP12
+}
+
 /*
-Функция проверки email на корректность
+Функция получения действия запроса: заголовок SOAPAction для SOAP 1.1,
+параметр action в Content-Type для SOAP 1.2
 */
-func validateEmail(email string) bool {
-	_, err := mail.ParseAddress(email)
+func requestAction(c *gin.Context, version models.SOAPVersion) string {
+	if version == models.SOAP11 {
+		return strings.Trim(c.GetHeader("SOAPAction"), `"`)
+	}
+	_, params, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
 	if err != nil {
-		return false
+		return ""
 	}
-	return true
+	return params["action"]
+}
+
+// Добавление блока в заголовок ответа
+func addResponseHeader(c *gin.Context, block any) {
+	var blocks []any
+	if v, ok := c.Get(responseHeaderKey); ok {
+		blocks = v.([]any)
+	}
+	c.Set(responseHeaderKey, append(blocks, block))
+}
+
+/*
+Блоки WS-Addressing ответа на запрос с WS-Addressing: действие ответа операции
+(или действие Fault), новый MessageID и RelatesTo с MessageID запроса
+*/
+func addressingHeaders(c *gin.Context, response any) []any {
+	v, ok := c.Get(addressingKey)
+	if !ok {
+		return nil
+	}
+	headers := v.(RequestHeaders)
+	action := models.WSAddressingFaultAction
+	if _, fault := response.(models.Fault); !fault {
+		action = c.GetString(responseActionKey)
+	}
+	blocks := []any{
+		models.NewAddressingHeader("Action", action),
+		models.NewAddressingHeader("MessageID", "urn:uuid:"+uuid.NewString()),
+	}
+	if headers.MessageID != "" {
+		blocks = append(blocks, models.NewAddressingHeader("RelatesTo", headers.MessageID))
+	}
+	return blocks
+}
+
+/*
+Функция отправки ответа операции или Fault внутри Envelope/Body в версии SOAP запроса.
+Блоки WS-Addressing и блоки, добавленные addResponseHeader, передаются в env:Header.
+Для SOAP 1.1 Fault передается в формате 1.1 с HTTP статусом 500
+*/
+func writeSOAPResponse(c *gin.Context, status int, response any) {
+	version := soapVersion(c)
+	blocks := addressingHeaders(c, response)
+	if v, ok := c.Get(responseHeaderKey); ok {
+		blocks = append(blocks, v.([]any)...)
+	}
+	if fault, ok := response.(models.Fault); ok {
+		requestLogger(c).Info("Response fault", zap.Int("status", status), zap.String("reason", fault.Reason.Text.Value))
+	}
+	if fault, ok := response.(models.Fault); ok && version == models.SOAP11 {
+		response = fault.SOAP11()
+		status = http.StatusInternalServerError
+	}
+	envelope := models.ResponseEnvelope{
+		XmlnsEnv: version.Namespace(),
+		Body:     models.ResponseBody{Content: response},
+	}
+	if len(blocks) > 0 {
+		envelope.Header = &models.ResponseHeader{Blocks: blocks}
+	}
+	out, err := xml.Marshal(envelope)
+	if err != nil {
+		requestLogger(c).Error("Error encoding SOAP response", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Error encoding response")
+		return
+	}
+	c.Data(status, version.ContentType(), append([]byte(xml.Header), out...))
 }
 
 /*
-Функция проверки телефона на корректность
+Функция формирования Fault ValidationFailed со всеми нарушениями правил проверки
 */
-func validatePhone(phone string) bool {
-	re := regexp.MustCompile(`^\+7\d{10}$`)
-	return re.MatchString(phone)
+func writeValidationFault(c *gin.Context, err error) {
+	requestLogger(c).Info("Validation failed", zap.Error(err))
+	fault := newSOAPFault(models.FaultCodeSender, models.ErrorValidationFailedSubcode, models.ErrorValidationFailedMessage, models.ErrorValidationFailedCode, models.ErrorValidationFailedDetail)
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		fault.Detail.Violations = invalid.Violations
+	}
+	writeSOAPResponse(c, http.StatusBadRequest, fault)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 
-func (h *StorageHandler) BasicAuth(c *gin.Context) bool {
-	auth := c.Request.Header.Get("Authorization")
-	if auth == "" {
-		c.String(http.StatusUnauthorized, "Authorization header is missing")
-		return false
+/*
+Проверка HTTP Basic аутентификации: возвращает пользователя или ошибку
+(auth.ErrNoCredentials без заголовка Authorization). Ответ клиенту (Fault) формирует вызывающий обработчик
+*/
+func (h *StorageHandler) BasicAuth(c *gin.Context) (*models.User, error) {
+	header := c.Request.Header.Get("Authorization")
+	if header == "" {
+		requestLogger(c).Info("Authorization header is missing")
+		return nil, auth.ErrNoCredentials
 	}
 
 	const prefix = "Basic "
-	if !strings.HasPrefix(auth, prefix) {
-		c.String(http.StatusUnauthorized, "Authorization header must start with 'Basic'")
-		return false
+	if !strings.HasPrefix(header, prefix) {
+		requestLogger(c).Info("Authorization header must start with 'Basic'")
+		return nil, auth.ErrInvalidCredentials
 	}
 
-	payload, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	payload, err := base64.StdEncoding.DecodeString(header[len(prefix):])
 	if err != nil {
-		c.String(http.StatusUnauthorized, "Invalid base64 encoding")
-		return false
+		requestLogger(c).Info("Invalid base64 encoding", zap.Error(err))
+		return nil, auth.ErrInvalidCredentials
 	}
 
 	pair := strings.SplitN(string(payload), ":", 2)
 	if len(pair) != 2 {
-		c.String(http.StatusUnauthorized, "Invalid authorization format")
-		return false
+		requestLogger(c).Info("Invalid authorization format")
+		return nil, auth.ErrInvalidCredentials
 	}
 
 	username, password := pair[0], pair[1]
-	if !validateCredentials(username, password) { 
-		c.String(http.StatusUnauthorized, "Invalid username or password")
-		return false
+	user, err := auth.Authenticate(h.Storage.UserRepository, username, password)
+	if err != nil {
+		requestLogger(c).Info("Authentication failed", zap.String("username", username), zap.Error(err))
+		return nil, err
 	}
-	return true
+	return user, nil
 }
 
+/*
+Аутентификация запроса: UsernameToken из заголовка wsse:Security имеет приоритет,
+иначе используется HTTP Basic. Обе схемы проверяются по одному хранилищу пользователей
+*/
+func (h *StorageHandler) authenticate(c *gin.Context, headers RequestHeaders) (*models.User, error) {
+	if headers.Security == nil || headers.Security.UsernameToken == nil {
+		return h.BasicAuth(c)
+	}
+	token := headers.Security.UsernameToken
+	user, err := h.Tokens.Authenticate(token)
+	if errors.Is(err, auth.ErrLegacyDigestSecret) {
+		requestLogger(c).Warn("Unencrypted digest secret rejected: set it again with \"user digest\"", zap.String("username", token.Username))
+	}
+	if err != nil {
+		requestLogger(c).Info("UsernameToken authentication failed", zap.String("username", token.Username), zap.Error(err))
+		return nil, err
+	}
+	return user, nil
+}
 
-func validateCredentials(username, password string) bool {
-	const validUsername = "root"
-	const validPassword = "password"
-	return username == validUsername && password == validPassword
+/*
+Хранилище записей от имени пользователя запроса (пусто для анонимного запроса)
+*/
+func (h *StorageHandler) persons(c *gin.Context) database.PersonRepository {
+	actor := database.Actor{ClientIP: c.ClientIP()}
+	if v, ok := c.Get(userKey); ok {
+		actor.Username = v.(*models.User).Username
+	}
+	if v, ok := c.Get(addressingKey); ok {
+		actor.MessageID = v.(RequestHeaders).MessageID
+	}
+	return h.Storage.PersonRepository.WithActor(actor)
 }
 
 //////////////////////////////////////////////////////////////////////////////
@@ -106,51 +253,143 @@ func (sh *StorageHandler) SOAPHandler(c *gin.Context) {
 
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "Error reading request body")
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
 	if err := xml.Unmarshal(body, &envelope); err != nil {
-		fmt.Println("Error decoding XML:", err)
-		c.String(http.StatusBadRequest, "Invalid request")
+		requestLogger(c).Info("Error decoding XML", zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidRequestSubcode, models.ErrorInvalidRequestMessage, models.ErrorInvalidRequestCode, models.ErrorInvalidRequestDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
 		return
 	}
 
-	fmt.Printf("Decoded Envelope: %+v\n", envelope)
+	//Определяем версию SOAP по пространству имен конверта
+	version, ok := models.SOAPVersionByNamespace(envelope.XMLName.Space)
+	if !ok {
+		requestLogger(c).Info("Unsupported envelope namespace", zap.String("namespace", envelope.XMLName.Space))
+		fault := newSOAPFault(models.FaultCodeVersionMismatch, "", models.ErrorVersionMismatchMessage, models.ErrorVersionMismatchCode, models.ErrorVersionMismatchDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		return
+	}
+	c.Set(soapVersionKey, version)
+
+	//Обрабатываем блоки заголовка; обязательный блок без обработчика - Fault MustUnderstand.
+	//Блоки NotUnderstood определены только в SOAP 1.2
+	headers, notUnderstood, err := Headers.Process(version, envelope.Header)
+	if len(notUnderstood) > 0 {
+		for _, name := range notUnderstood {
+			requestLogger(c).Info("Mandatory header not understood", zap.String("header", name.Local), zap.String("namespace", name.Space))
+			if version == models.SOAP12 {
+				addResponseHeader(c, models.NewNotUnderstood(name))
+			}
+		}
+		fault := newSOAPFault(models.FaultCodeMustUnderstand, "", models.ErrorMustUnderstandMessage, models.ErrorMustUnderstandCode, models.ErrorMustUnderstandDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		return
+	}
+	//Запрос с WS-Addressing: ответ содержит wsa:RelatesTo, записи журнала - MessageID
+	if headers.Addressing {
+		c.Set(addressingKey, headers)
+		if headers.MessageID != "" {
+			c.Set(loggerKey, logging.Logger.With(zap.String("messageId", headers.MessageID)))
+		}
+		requestLogger(c).Info("WS-Addressing request", zap.String("action", headers.Action), zap.String("to", headers.To), zap.String("replyTo", headers.ReplyTo))
+	}
+	if errors.Is(err, ErrOnlyAnonymousAddress) {
+		requestLogger(c).Info("Unsupported reply address", zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorOnlyAnonymousAddressSubcode, models.ErrorOnlyAnonymousAddressMessage, models.ErrorOnlyAnonymousAddressCode, models.ErrorOnlyAnonymousAddressDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+		return
+	}
+	if err != nil {
+		requestLogger(c).Info("Error processing header", zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidRequestSubcode, models.ErrorInvalidRequestMessage, models.ErrorInvalidRequestCode, models.ErrorInvalidRequestDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+		return
+	}
 
-	switch {
-	case envelope.Body.AddPerson != nil:
-		sh.addPersonHandler(c, envelope.Body.AddPerson)
-	case envelope.Body.DeletePerson != nil:
-		sh.deletePersonHandler(c, envelope.Body.DeletePerson)
-	case envelope.Body.UpdatePerson != nil:
-		sh.updatePersonHandler(c, envelope.Body.UpdatePerson)
-	case envelope.Body.GetPerson != nil:
-		sh.getPersonHandler(c, envelope.Body.GetPerson)
-	case envelope.Body.GetAllPersons != nil:
-		sh.getAllPersonsHandler(c)
-	case envelope.Body.SearchPerson != nil:
-		sh.searchPersonHandler(c, envelope.Body.SearchPerson)
+	action := requestAction(c, version)
+	//Действие из заголовка WS-Addressing имеет приоритет над транспортным
+	if headers.Action != "" {
+		action = headers.Action
+	}
+	c.Set(soapActionKey, action)
+
+	//Конверт целиком не логируется: заголовок wsse:Security содержит пароль
+	requestLogger(c).Debug("Decoded envelope", zap.String("namespace", version.Namespace()), zap.String("action", action))
+
+	//Тело должно содержать ровно один элемент операции
+	switch len(envelope.Body.Elements) {
+	case 0:
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorEmptyBodySubcode, models.ErrorEmptyBodyMessage, models.ErrorEmptyBodyCode, models.ErrorEmptyBodyDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+		return
+	case 1:
 	default:
-		fmt.Println("Unsupported action")
-		c.String(http.StatusBadRequest, "Unsupported action")
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorMultipleOperationsSubcode, models.ErrorMultipleOperationsMessage, models.ErrorMultipleOperationsCode, models.ErrorMultipleOperationsDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
 		return
 	}
-}
+	element := envelope.Body.Elements[0]
 
-// Метод добавления новой записи в базу данных
-func (h *StorageHandler) addPersonHandler(c *gin.Context, request *models.AddPersonRequest) {
-	//////////////////////////////////////
-	if !h.BasicAuth(c) {
-		logging.Logger.Error("Error Invalid user login or password")
-		fault := createSOAPFault("soap:Client", models.ErrorAuthIncorrectMessage, models.ErrorAuthIncorrectCode, models.ErrorAuthIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusUnauthorized, fault)
+	//Ищем операцию по имени элемента в реестре
+	op, ok := Operations.Lookup(element.Name)
+	if !ok {
+		requestLogger(c).Info("Unsupported operation", zap.String("element", element.Name.Local), zap.String("namespace", element.Name.Space))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorUnsupportedOperationSubcode, models.ErrorUnsupportedOperationMessage, models.ErrorUnsupportedOperationCode, models.ErrorUnsupportedOperationDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+		return
+	}
 
+	//Если клиент указал действие, оно должно соответствовать операции в теле
+	if action != "" {
+		if actionOp, ok := Operations.LookupAction(action); !ok || actionOp != op {
+			requestLogger(c).Info("Action does not match operation", zap.String("action", action), zap.String("operation", op.Name.Local))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorActionMismatchSubcode, models.ErrorActionMismatchMessage, models.ErrorActionMismatchCode, models.ErrorActionMismatchDetail)
+			writeSOAPResponse(c, http.StatusBadRequest, fault)
+			return
+		}
+	}
+
+	c.Set(responseActionKey, op.ResponseAction)
+
+	request := op.newRequest()
+	if err := element.Decode(request); err != nil {
+		requestLogger(c).Info("Error decoding operation", zap.String("operation", op.Name.Local), zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidRequestSubcode, models.ErrorInvalidRequestMessage, models.ErrorInvalidRequestCode, models.ErrorInvalidRequestDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
 		return
 	}
-	//////////////////////////////////////
+
+	//Проверяем разрешение роли пользователя на операцию по политике реестра.
+	//Запрос без учетных данных выполняется с анонимной ролью из конфигурации
+	if permission := op.permission(request); permission != "" {
+		user, err := sh.authenticate(c, headers)
+		switch {
+		case errors.Is(err, auth.ErrNoCredentials) && auth.HasPermission(config.GeneralServerSetting.AnonymousRole, permission):
+		case err != nil:
+			requestLogger(c).Error("Error Invalid user login or password")
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorAuthIncorrectSubcode, models.ErrorAuthIncorrectMessage, models.ErrorAuthIncorrectCode, models.ErrorAuthIncorrectDetail)
+			writeSOAPResponse(c, http.StatusUnauthorized, fault)
+			return
+		case !auth.HasPermission(user.Role, permission):
+			requestLogger(c).Info("Permission denied", zap.String("username", user.Username), zap.String("role", user.Role), zap.String("operation", op.Name.Local))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorPermissionDeniedSubcode, models.ErrorPermissionDeniedMessage, models.ErrorPermissionDeniedCode, models.ErrorPermissionDeniedDetail)
+			writeSOAPResponse(c, http.StatusForbidden, fault)
+			return
+		default:
+			c.Set(userKey, user)
+		}
+	}
+
+	op.handle(sh, c, request)
+}
+
+// Метод добавления новой записи в базу данных
+func (h *StorageHandler) addPersonHandler(c *gin.Context, request *models.AddPersonRequest) {
 	// Создаем person с данными из запроса
 	person := models.Person{
 		Name:      request.Name,
@@ -159,162 +398,139 @@ func (h *StorageHandler) addPersonHandler(c *gin.Context, request *models.AddPer
 		Email:     request.Email,
 		Telephone: request.Telephone,
 	}
-	if !validateEmail(person.Email) {
-		logging.Logger.Info("Email is", zap.String("Email:", request.Email))
-		fault := createSOAPFault("soap:Client", models.ErrorEmailIncorrectMessage, models.ErrorEmailIncorrectCode, models.ErrorEmailIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusConflict, fault)
-		return
-	}
-
-	if !validatePhone(person.Telephone) {
-		logging.Logger.Info("Email exists", zap.String("Email:", request.Email))
-		fault := createSOAPFault("soap:Client", models.ErrorPhoneNumberIncorrectMessage, models.ErrorPhoneNumberIncorrectCode, models.ErrorPhoneNumberIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusConflict, fault)
+	//Проверяем поля по правилам из конфигурации
+	if err := h.Rules.Check(&person); err != nil {
+		writeValidationFault(c, err)
 		return
 	}
 
 	// Добавляем person в базу данных
-	id, err := h.Storage.PersonRepository.AddPerson(&person)
+	id, err := h.persons(c).AddPerson(&person)
 	if err != nil {
 		if errors.Is(err, database.ErrEmailExists) {
-			logging.Logger.Info("Email exists", zap.String("Email:", request.Email), zap.Error(err))
-			fault := createSOAPFault("soap:Client", models.ErrorRecordEmailExistsMessage, models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsDetail)
-			fmt.Printf("Response Fault: %+v\n", fault)
-			c.XML(http.StatusConflict, fault)
+			requestLogger(c).Info("Email exists", zap.String("Email:", request.Email), zap.Error(err))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordEmailExistsSubcode, models.ErrorRecordEmailExistsMessage, models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsDetail)
+			writeSOAPResponse(c, http.StatusConflict, fault)
 			return
 		}
-		fmt.Printf("Error adding person: %v\n", err)
+		requestLogger(c).Error("Error adding person", zap.Error(err))
 
 		// Формируем SOAP Fault для ошибки добавления
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
-	fmt.Printf("Person added with ID: %d\n", id)
+	requestLogger(c).Info("Person added", zap.Uint("ID", id))
 
 	response := models.AddPersonResponse{
 		ID: id,
 	}
 
 	// Возвращаем успешный ответ в формате XML
-	fmt.Printf("Response: %+v\n", response)
-	c.XML(http.StatusOK, response)
+	writeSOAPResponse(c, http.StatusOK, response)
 }
 
 // Метод обновления записи в базе данных
 func (h *StorageHandler) updatePersonHandler(c *gin.Context, request *models.UpdatePersonRequest) {
-	//////////////////////////////////////
-	if !h.BasicAuth(c) {
-		logging.Logger.Error("Error Invalid user login or password")
-		fault := createSOAPFault("soap:Client", models.ErrorAuthIncorrectMessage, models.ErrorAuthIncorrectCode, models.ErrorAuthIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusUnauthorized, fault)
+	//Проверяем версию, режим обновления и поля по правилам из конфигурации (в режиме patch - только переданные)
+	switch err := checkUpdate(h.Rules, request); {
+	case errors.Is(err, errNoVersion):
+		requestLogger(c).Info("Version is missing", zap.Uint("ID", request.ID))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorVersionRequiredSubcode, models.ErrorVersionRequiredMessage, models.ErrorVersionRequiredCode, models.ErrorVersionRequiredDetail)
+		writeSOAPResponse(c, http.StatusPreconditionRequired, fault)
 		return
-	}
-	//////////////////////////////////////
-	//Проверяем на корректность Email
-	if !validateEmail(request.Email) {
-		logging.Logger.Info("Email is", zap.String("Email:", request.Email))
-		fault := createSOAPFault("soap:Client", models.ErrorEmailIncorrectMessage, models.ErrorEmailIncorrectCode, models.ErrorEmailIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusConflict, fault)
+	case errors.Is(err, errInvalidUpdateMode):
+		requestLogger(c).Info("Invalid update mode", zap.String("mode", request.Mode))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidUpdateModeSubcode, models.ErrorInvalidUpdateModeMessage, models.ErrorInvalidUpdateModeCode, models.ErrorInvalidUpdateModeDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
 		return
-	}
-	//Проверяем на корректность номер телефона
-	if !validatePhone(request.Telephone) {
-		logging.Logger.Info("Email exists", zap.String("Email:", request.Email))
-		fault := createSOAPFault("soap:Client", models.ErrorPhoneNumberIncorrectMessage, models.ErrorPhoneNumberIncorrectCode, models.ErrorPhoneNumberIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusConflict, fault)
+	case err != nil:
+		writeValidationFault(c, err)
 		return
 	}
 	// Проверяем, существует ли запись с данным ID
-	checkByID, err := h.Storage.PersonRepository.CheckPersonByID(uint(request.ID))
+	checkByID, err := h.persons(c).CheckPersonByID(uint(request.ID))
 	if !checkByID {
-		fault := createSOAPFault("soap:Client", models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusNotFound, fault)
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
 		return
 	}
 	if err != nil {
-		logging.Logger.Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
+		requestLogger(c).Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
 
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
 
-	// Создаем объект типа Person на основе запроса
-	person := models.Person{
-		ID:        uint(request.ID),
-		Name:      request.Name,
-		Surname:   request.Surname,
-		Age:       request.Age,
-		Email:     request.Email,
-		Telephone: request.Telephone,
-	}
-
-	// Обновляем информацию о человеке в базе данных
-	err = h.Storage.PersonRepository.UpdatePerson(&person)
+	// Обновляем информацию о человеке в базе данных: все поля или, в режиме patch, переданные
+	person, err := updatePerson(h.persons(c), request)
 	if err != nil {
 		// Проверяем, существует ли запись с данным Email кроме обновляемой
 		if errors.Is(err, database.ErrEmailExists) {
-			logging.Logger.Info("Email exists", zap.String("Email:", request.Email), zap.Error(err))
-			fault := createSOAPFault("soap:Client", models.ErrorRecordEmailExistsMessage, models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsDetail)
-			fmt.Printf("Response Fault: %+v\n", fault)
-			c.XML(http.StatusConflict, fault)
+			requestLogger(c).Info("Email exists", zap.String("Email:", request.Email.Value), zap.Error(err))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordEmailExistsSubcode, models.ErrorRecordEmailExistsMessage, models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsDetail)
+			writeSOAPResponse(c, http.StatusConflict, fault)
+			return
+		}
+		// Запись удалена параллельным запросом после проверки
+		if errors.Is(err, database.ErrPersonNotFound) {
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+			writeSOAPResponse(c, http.StatusNotFound, fault)
 			return
 		}
-		logging.Logger.Error("Error updating person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
+		// Запись изменена другим запросом после чтения клиентом
+		if errors.Is(err, database.ErrVersionConflict) {
+			requestLogger(c).Info("Version conflict", zap.Uint("ID", request.ID), zap.Uint("version", request.Version))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorVersionConflictSubcode, models.ErrorVersionConflictMessage, models.ErrorVersionConflictCode, models.ErrorVersionConflictDetail)
+			writeSOAPResponse(c, http.StatusPreconditionFailed, fault)
+			return
+		}
+		requestLogger(c).Error("Error updating person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
 
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
-	fmt.Println("Person updated with ID:")
-	logging.Logger.Info("Successfully updated person with ID", zap.Uint("ID", uint(request.ID)))
+	requestLogger(c).Info("Successfully updated person with ID", zap.Uint("ID", uint(request.ID)))
 
 	response := models.UpdatePersonResponse{
-		Status: true,
+		Status:  true,
+		Version: person.Version,
 	}
 
 	// Возвращаем результат в формате XML
-	fmt.Printf("Response: %+v\n", response)
-	c.XML(http.StatusOK, response)
+	writeSOAPResponse(c, http.StatusOK, response)
 }
 
 func (h *StorageHandler) getPersonHandler(c *gin.Context, request *models.GetPersonRequest) {
+	if request.AsOf != "" {
+		h.getPersonAsOf(c, request)
+		return
+	}
 	// Получаем информацию о человеке по ID
-	person, err := h.Storage.PersonRepository.GetPerson(request.ID)
+	person, err := h.persons(c).GetPerson(request.ID)
 	if err != nil {
 
 		if errors.Is(err, database.ErrPersonNotFound) {
-			fault := createSOAPFault("soap:Client", models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
-			fmt.Printf("Response Fault: %+v\n", fault)
-			c.XML(http.StatusNotFound, fault)
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+			writeSOAPResponse(c, http.StatusNotFound, fault)
 			return
 		}
 
-		logging.Logger.Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
+		requestLogger(c).Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
 		// Формируем SOAP Fault при ошибке
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
 
 	// Если записи не найдены, формируем SOAP Fault для клиента
 	if person == nil {
-		fmt.Printf("No person found with ID %d\n", request.ID)
+		requestLogger(c).Info("No person found with ID", zap.Uint("ID", uint(request.ID)))
 
-		fault := createSOAPFault("soap:Client", models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusNotFound, fault)
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
 		return
 	}
 
@@ -324,122 +540,383 @@ func (h *StorageHandler) getPersonHandler(c *gin.Context, request *models.GetPer
 	}
 
 	// Возвращаем результат в формате XML
-	fmt.Printf("Response: %+v\n", response)
-	c.XML(http.StatusOK, response)
+	writeSOAPResponse(c, http.StatusOK, response)
+}
+
+/*
+Функция разбора параметров постраничного вывода; при ошибке отправляет Fault
+*/
+func pageRequest(c *gin.Context, paging models.Paging) (database.PageRequest, bool) {
+	page, err := database.NewPageRequest(paging.PageSize, paging.PageToken, paging.SortBy, paging.SortOrder)
+	if err != nil {
+		writeInvalidPagingFault(c, err)
+		return page, false
+	}
+	return page, true
+}
+
+func writeInvalidPagingFault(c *gin.Context, err error) {
+	requestLogger(c).Info("Invalid paging parameters", zap.Error(err))
+	fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidPagingSubcode, models.ErrorInvalidPagingMessage, models.ErrorInvalidPagingCode, models.ErrorInvalidPagingDetail)
+	writeSOAPResponse(c, http.StatusBadRequest, fault)
 }
 
 // Метод получения всех записей
-func (h *StorageHandler) getAllPersonsHandler(c *gin.Context) {
-	// Получаем все записи из базы
-	persons, err := h.Storage.PersonRepository.GetAllPersons()
+func (h *StorageHandler) getAllPersonsHandler(c *gin.Context, request *models.GetAllPersonsRequest) {
+	page, ok := pageRequest(c, request.Paging)
+	if !ok {
+		return
+	}
+	// Получаем страницу записей из базы
+	persons, err := h.persons(c).GetAllPersons(page)
+	if errors.Is(err, database.ErrInvalidPageToken) {
+		writeInvalidPagingFault(c, err)
+		return
+	}
 	if err != nil {
-		logging.Logger.Error("Error getting all persons", zap.Error(err))
+		requestLogger(c).Error("Error getting all persons", zap.Error(err))
 
 		// Формируем SOAP Fault для ошибки получения
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
 
 	// Если записи не найдены, формируем SOAP Fault для клиента
-	if len(persons) == 0 {
-		fmt.Println("No persons found.")
+	if persons.TotalCount == 0 {
+		requestLogger(c).Info("No persons found")
 
-		fault := createSOAPFault("soap:Client", models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusNotFound, fault)
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
 		return
 	}
 
 	response := models.GetAllPersonsResponse{
-		Persons: persons,
+		Persons:  persons.Persons,
+		PageInfo: models.PageInfo{TotalCount: persons.TotalCount, NextPageToken: persons.NextPageToken},
 	}
 
 	// Возвращаем результат в формате XML
-	fmt.Printf("Response: %+v\n", response)
-	c.XML(http.StatusOK, response)
+	writeSOAPResponse(c, http.StatusOK, response)
 }
 
 // Метод удаления записи по ID
 func (h *StorageHandler) deletePersonHandler(c *gin.Context, request *models.DeletePersonRequest) {
-	//////////////////////////////////////
-	if !h.BasicAuth(c) {
-		logging.Logger.Error("Error Invalid user login or password")
-		fault := createSOAPFault("soap:Client", models.ErrorAuthIncorrectMessage, models.ErrorAuthIncorrectCode, models.ErrorAuthIncorrectDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusUnauthorized, fault)
-		return
-	}
-	//////////////////////////////////////
 	//Проверяем существование записи по ID, если нет, формируем SOAP Fault
-	checkByID, err := h.Storage.PersonRepository.CheckPersonByID(uint(request.ID))
+	checkByID, err := h.persons(c).CheckPersonByID(uint(request.ID))
 	if !checkByID {
-		logging.Logger.Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
-		fault := createSOAPFault("soap:Client", models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusNotFound, fault)
+		requestLogger(c).Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
 		return
 	}
 	if err != nil {
-		logging.Logger.Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
+		requestLogger(c).Error("Error getting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
 
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
 
 	//Удаляем запись по ID из базы
-	err = h.Storage.PersonRepository.DeletePerson(request)
+	err = h.persons(c).DeletePerson(uint(request.ID))
+	// Запись удалена параллельным запросом после проверки
+	if errors.Is(err, database.ErrPersonNotFound) {
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
+		return
+	}
 	if err != nil {
-		logging.Logger.Error("Error deleting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
+		requestLogger(c).Error("Error deleting person with ID", zap.Uint("ID", uint(request.ID)), zap.Error(err))
 
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
 
-	logging.Logger.Info("Successfully deleted person with ID", zap.Uint("ID", uint(request.ID)))
+	requestLogger(c).Info("Successfully deleted person with ID", zap.Uint("ID", uint(request.ID)))
 	//Формируем статус в формате SOAP
 
-	response := models.UpdatePersonResponse{
+	response := models.DeletePersonResponse{
 		Status: true,
 	}
-	fmt.Printf("Response: %+v\n", response)
-	c.XML(http.StatusOK, response)
+	writeSOAPResponse(c, http.StatusOK, response)
 
 }
 
-// Метод поиска записей по запросу
-func (h *StorageHandler) searchPersonHandler(c *gin.Context, request *models.SearchPersonRequest) {
+// Метод восстановления удаленной записи по ID
+func (h *StorageHandler) restorePersonHandler(c *gin.Context, request *models.RestorePersonRequest) {
+	person, err := h.persons(c).RestorePerson(request.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrPersonNotFound):
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+			writeSOAPResponse(c, http.StatusNotFound, fault)
+		case errors.Is(err, database.ErrEmailExists):
+			requestLogger(c).Info("Email of restored person is taken", zap.Uint("ID", request.ID))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordEmailExistsSubcode, models.ErrorRecordEmailExistsMessage, models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsDetail)
+			writeSOAPResponse(c, http.StatusConflict, fault)
+		default:
+			requestLogger(c).Error("Error restoring person with ID", zap.Uint("ID", request.ID), zap.Error(err))
+			fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+			writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		}
+		return
+	}
+	requestLogger(c).Info("Successfully restored person with ID", zap.Uint("ID", request.ID))
+	writeSOAPResponse(c, http.StatusOK, models.RestorePersonResponse{Person: *person})
+}
+
+// Метод окончательного удаления записи, ранее удаленной DeletePerson
+func (h *StorageHandler) purgePersonHandler(c *gin.Context, request *models.PurgePersonRequest) {
+	err := h.persons(c).PurgePerson(request.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrPersonNotFound) {
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+			writeSOAPResponse(c, http.StatusNotFound, fault)
+			return
+		}
+		requestLogger(c).Error("Error purging person with ID", zap.Uint("ID", request.ID), zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		return
+	}
+	requestLogger(c).Info("Successfully purged person with ID", zap.Uint("ID", request.ID))
+	writeSOAPResponse(c, http.StatusOK, models.PurgePersonResponse{Status: true})
+}
+
+/*
+Метод получения записи на момент AsOf, восстановленной по журналу изменений
+*/
+func (h *StorageHandler) getPersonAsOf(c *gin.Context, request *models.GetPersonRequest) {
+	asOf, err := database.ParseAsOf(request.AsOf)
+	if err != nil {
+		requestLogger(c).Info("Invalid AsOf", zap.String("AsOf", request.AsOf))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidAsOfSubcode, models.ErrorInvalidAsOfMessage, models.ErrorInvalidAsOfCode, models.ErrorInvalidAsOfDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+		return
+	}
+	history, err := h.Storage.PersonRepository.PersonHistory(request.ID)
+	var person *models.Person
+	if err == nil {
+		person, err = database.PersonAt(history, asOf)
+	}
+	if err != nil {
+		if !revisionFault(c, err) {
+			requestLogger(c).Error("Error getting person with ID as of", zap.Uint("ID", request.ID), zap.String("AsOf", request.AsOf), zap.Error(err))
+			fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+			writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		}
+		return
+	}
+	requestLogger(c).Info("Successfully got person with ID as of", zap.Uint("ID", request.ID), zap.String("AsOf", request.AsOf), zap.Uint("revision", person.Version))
+	writeSOAPResponse(c, http.StatusOK, models.GetPersonResponse{Person: *person})
+}
 
-	persons, err := h.Storage.PersonRepository.SearchPerson(request.Query)
+// Fault для отсутствующей записи или ревизии; false - ошибка другого вида
+func revisionFault(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, database.ErrRevisionNotFound):
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRevisionNotFoundSubcode, models.ErrorRevisionNotFoundMessage, models.ErrorRevisionNotFoundCode, models.ErrorRevisionNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
+	case errors.Is(err, database.ErrPersonNotFound):
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
+	default:
+		return false
+	}
+	return true
+}
+
+/*
+Метод возврата записи к прежней ревизии: значения ревизии сохраняются как новое обновление
+с проверкой текущей версии записи
+*/
+func (h *StorageHandler) revertPersonHandler(c *gin.Context, request *models.RevertPersonRequest) {
+	if request.Version == 0 {
+		requestLogger(c).Info("Version is missing", zap.Uint("ID", request.ID))
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorVersionRequiredSubcode, models.ErrorVersionRequiredMessage, models.ErrorVersionRequiredCode, models.ErrorVersionRequiredDetail)
+		writeSOAPResponse(c, http.StatusPreconditionRequired, fault)
+		return
+	}
+	person, err := database.RevertPerson(h.persons(c), request.ID, request.Revision, request.Version, func(person *models.Person) error {
+		//Ревизия могла быть сохранена до изменения правил проверки
+		return h.Rules.Check(person)
+	})
 	if err != nil {
-		logging.Logger.Error("Error searching for persons with query", zap.String("query", request.Query), zap.Error(err))
+		var invalid *validation.Error
+		switch {
+		case errors.As(err, &invalid):
+			writeValidationFault(c, err)
+		case revisionFault(c, err):
+		case errors.Is(err, database.ErrEmailExists):
+			requestLogger(c).Info("Email of reverted person is taken", zap.Uint("ID", request.ID), zap.Uint("revision", request.Revision))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordEmailExistsSubcode, models.ErrorRecordEmailExistsMessage, models.ErrorRecordEmailExistsCode, models.ErrorRecordEmailExistsDetail)
+			writeSOAPResponse(c, http.StatusConflict, fault)
+		case errors.Is(err, database.ErrVersionConflict):
+			requestLogger(c).Info("Version conflict", zap.Uint("ID", request.ID), zap.Uint("version", request.Version))
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorVersionConflictSubcode, models.ErrorVersionConflictMessage, models.ErrorVersionConflictCode, models.ErrorVersionConflictDetail)
+			writeSOAPResponse(c, http.StatusPreconditionFailed, fault)
+		default:
+			requestLogger(c).Error("Error reverting person with ID", zap.Uint("ID", request.ID), zap.Uint("revision", request.Revision), zap.Error(err))
+			fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+			writeSOAPResponse(c, http.StatusInternalServerError, fault)
+		}
+		return
+	}
+	requestLogger(c).Info("Successfully reverted person with ID", zap.Uint("ID", request.ID), zap.Uint("revision", request.Revision), zap.Uint("version", person.Version))
+	writeSOAPResponse(c, http.StatusOK, models.RevertPersonResponse{Person: *person})
+}
 
-		fault := createSOAPFault("soap:Server", "Internal Server Error", "500", "An unexpected error occurred.")
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusInternalServerError, fault)
+/*
+Метод получения журнала изменений записи. Записи без журнала (добавленные до его ведения)
+возвращаются с пустым журналом, если существуют
+*/
+func (h *StorageHandler) getPersonHistoryHandler(c *gin.Context, request *models.GetPersonHistoryRequest) {
+	entries, err := h.Storage.PersonRepository.PersonHistory(request.ID)
+	if err == nil && len(entries) == 0 {
+		_, err = h.Storage.PersonRepository.CheckPersonByID(request.ID)
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrPersonNotFound) {
+			fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+			writeSOAPResponse(c, http.StatusNotFound, fault)
+			return
+		}
+		requestLogger(c).Error("Error getting history of person with ID", zap.Uint("ID", request.ID), zap.Error(err))
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
 		return
 	}
+	requestLogger(c).Info("Successfully got history of person with ID", zap.Uint("ID", request.ID), zap.Int("entries", len(entries)))
+	writeSOAPResponse(c, http.StatusOK, models.GetPersonHistoryResponse{Entries: entries})
+}
 
-	if len(persons) == 0 {
-		fmt.Println("No persons found.")
+// Метод поиска записей по запросу
+func (h *StorageHandler) searchPersonHandler(c *gin.Context, request *models.SearchPersonRequest) {
 
-		fault := createSOAPFault("soap:Client", models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
-		fmt.Printf("Response Fault: %+v\n", fault)
-		c.XML(http.StatusNotFound, fault)
+	page, ok := pageRequest(c, request.Paging)
+	if !ok {
 		return
-	} else {
-		fmt.Printf("Found persons: %+v\n", persons)
 	}
+	//Ошибки разбора критериев и ошибки хранилища обрабатываются одинаково
+	persons, err := h.search(request, page)
+	switch {
+	case err == nil:
+		writeSearchResult(c, persons)
+	case errors.Is(err, database.ErrInvalidPageToken), errors.Is(err, database.ErrInvalidSort):
+		writeInvalidPagingFault(c, err)
+	case errors.Is(err, database.ErrEmptyQuery):
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorEmptyQuerySubcode, models.ErrorEmptyQueryMessage, models.ErrorEmptyQueryCode, models.ErrorEmptyQueryDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+	case errors.Is(err, database.ErrQueryTooLong):
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorQueryTooLongSubcode, models.ErrorQueryTooLongMessage, models.ErrorQueryTooLongCode, models.ErrorQueryTooLongDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+	case errors.Is(err, database.ErrInvalidInput):
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorInvalidSearchSubcode, models.ErrorInvalidSearchMessage, models.ErrorInvalidSearchCode, models.ErrorInvalidSearchDetail)
+		writeSOAPResponse(c, http.StatusBadRequest, fault)
+	default:
+		requestLogger(c).Error("Error searching for persons with query", zap.String("query", request.Query), zap.Error(err))
+
+		fault := newSOAPFault(models.FaultCodeReceiver, models.ErrorInternalSubcode, models.ErrorInternalMessage, models.ErrorInternalCode, models.ErrorInternalDetail)
+		writeSOAPResponse(c, http.StatusInternalServerError, fault)
+	}
+}
+
+/*
+Выполнение поиска в выбранном режиме
+*/
+func (h *StorageHandler) search(request *models.SearchPersonRequest, page database.PageRequest) (database.Page, error) {
+	switch strings.ToLower(strings.TrimSpace(request.Mode)) {
+	case "", models.SearchModeExact:
+		query, err := searchQuery(request)
+		if err != nil {
+			return database.Page{}, err
+		}
+		return h.Storage.PersonRepository.SearchPerson(query, page)
+	case models.SearchModeRanked:
+		//Ранжированный поиск только по строке Query, сортировка задается релевантностью
+		if request.Criteria != nil {
+			return database.Page{}, database.ErrInvalidInput
+		}
+		if request.SortBy != "" || request.SortOrder != "" {
+			return database.Page{}, database.ErrInvalidSort
+		}
+		return h.Storage.PersonRepository.RankedSearch(request.Query, page)
+	}
+	return database.Page{}, database.ErrInvalidInput
+}
+
+/*
+Функция построения запроса поиска: свободная строка Query или критерии Criteria
+*/
+func searchQuery(request *models.SearchPersonRequest) (database.SearchQuery, error) {
+	criteria := request.Criteria
+	if criteria == nil {
+		return database.FreeTextQuery(request.Query), nil
+	}
+	if strings.TrimSpace(request.Query) != "" {
+		return database.SearchQuery{}, database.ErrInvalidInput
+	}
+	var query database.SearchQuery
+	switch strings.ToLower(strings.TrimSpace(criteria.Match)) {
+	case "", "all":
+	case "any":
+		query.Any = true
+	default:
+		return query, database.ErrInvalidInput
+	}
+	fields := []struct {
+		name   string
+		filter *models.StringFilter
+	}{
+		{"name", criteria.Name},
+		{"surname", criteria.Surname},
+		{"email", criteria.Email},
+		{"telephone", criteria.Telephone},
+	}
+	for _, field := range fields {
+		if field.filter == nil {
+			continue
+		}
+		op := strings.ToLower(strings.TrimSpace(field.filter.Op))
+		if op == "" {
+			op = database.OpContains
+		}
+		query.Text = append(query.Text, database.TextCondition{
+			Field:      field.name,
+			Op:         op,
+			Value:      field.filter.Value,
+			IgnoreCase: field.filter.IgnoreCase,
+		})
+	}
+	if criteria.Age != nil {
+		query.MinAge, query.MaxAge = criteria.Age.Min, criteria.Age.Max
+	}
+	return query, nil
+}
+
+// Формирование ответа поиска; пустой результат - Fault RecordNotFound
+func writeSearchResult(c *gin.Context, persons database.Page) {
+	if persons.TotalCount == 0 {
+		requestLogger(c).Info("No persons found")
+
+		fault := newSOAPFault(models.FaultCodeSender, models.ErrorRecordNotFoundSubcode, models.ErrorRecordNotFoundMessage, models.ErrorRecordNotFoundCode, models.ErrorRecordNotFoundDetail)
+		writeSOAPResponse(c, http.StatusNotFound, fault)
+		return
+	}
+	requestLogger(c).Info("Found persons", zap.Int("count", len(persons.Persons)), zap.Int64("total", persons.TotalCount))
 
 	// Формируем результат в формате SOAP
 	response := models.SearchPersonResponse{
-		Persons: persons,
+		PageInfo: models.PageInfo{TotalCount: persons.TotalCount, NextPageToken: persons.NextPageToken},
+	}
+	for i, person := range persons.Persons {
+		result := models.ScoredPerson{Person: person}
+		if i < len(persons.Scores) {
+			result.Score = persons.Scores[i]
+		}
+		response.Persons = append(response.Persons, result)
 	}
-	fmt.Printf("Response: %+v\n", response)
-	c.XML(http.StatusOK, response)
+	writeSOAPResponse(c, http.StatusOK, response)
 }
@@ -0,0 +1,573 @@
+package handlers
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/auth"
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/database/memory"
+	"WST_lab1_server_new1/internal/logging"
+	"WST_lab1_server_new1/internal/models"
+	"WST_lab1_server_new1/internal/validation"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Пароль пользователей тестового сервера
+const testPassword = "secret"
+
+func TestMain(m *testing.M) {
+	logging.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+	config.GeneralServerSetting.AnonymousRole = models.RoleReader
+	os.Exit(m.Run())
+}
+
+/*
+Тестовый сервер с хранилищем в памяти и пользователями admin, editor и reader
+*/
+type testServer struct {
+	router  *gin.Engine
+	storage *database.Storage
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := memory.NewUserRepository()
+	for _, role := range []string{models.RoleAdmin, models.RoleEditor, models.RoleReader} {
+		if _, err := users.AddUser(&models.User{Username: role, PasswordHash: hash, Role: role}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, err := validation.New(config.ValidationConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &database.Storage{PersonRepository: memory.NewPersonRepository(), UserRepository: users}
+	handler := &StorageHandler{
+		Storage: storage,
+		Tokens:  &auth.TokenAuthenticator{Users: users, Nonces: auth.NewNonceCache()},
+		Rules:   rules,
+	}
+	router := gin.New()
+	router.POST("/soap", handler.SOAPHandler)
+	router.GET("/soap", handler.WSDLHandler)
+	return &testServer{router: router, storage: storage}
+}
+
+/*
+Отправка SOAP 1.2 запроса с операцией operation и телом body от имени user
+(пусто - без аутентификации). header - блоки заголовка конверта
+*/
+func (s *testServer) call(user string, operation string, body string, header string) (int, string) {
+	envelope := `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">` +
+		`<env:Header>` + header + `</env:Header>` +
+		`<env:Body><` + operation + ` xmlns="http://wst.lab/persons">` + body + `</` + operation + `></env:Body></env:Envelope>`
+	status, _, response := s.post(user, "application/soap+xml", "", envelope)
+	return status, response
+}
+
+/*
+Отправка конверта envelope как есть с заголовком Content-Type и, если задан,
+SOAPAction. Возвращает статус, Content-Type и тело ответа
+*/
+func (s *testServer) post(user string, contentType string, soapAction string, envelope string) (int, string, string) {
+	request := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(envelope))
+	request.Header.Set("Content-Type", contentType)
+	if soapAction != "" {
+		request.Header.Set("SOAPAction", `"`+soapAction+`"`)
+	}
+	if user != "" {
+		request.SetBasicAuth(user, testPassword)
+	}
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, request)
+	return recorder.Code, recorder.Header().Get("Content-Type"), recorder.Body.String()
+}
+
+// GET запрос описания сервиса с параметрами query
+func (s *testServer) get(query string) (int, string, string) {
+	request := httptest.NewRequest(http.MethodGet, "http://example.com/soap?"+query, nil)
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, request)
+	return recorder.Code, recorder.Header().Get("Content-Type"), recorder.Body.String()
+}
+
+const validPerson = `<Name>Иван</Name><Surname>Иванов</Surname><Age>30</Age><Email>ivan@mail.ru</Email><Telephone>+79001234567</Telephone>`
+
+func expect(t *testing.T, status int, body string, wantStatus int, fragments ...string) {
+	t.Helper()
+	if status != wantStatus {
+		t.Errorf("status = %d, want %d; body: %s", status, wantStatus, body)
+	}
+	for _, fragment := range fragments {
+		if !strings.Contains(body, fragment) {
+			t.Errorf("response does not contain %q: %s", fragment, body)
+		}
+	}
+}
+
+func TestAddAndGetPersonHandler(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.call(models.RoleEditor, "AddPerson", validPerson, "")
+	expect(t, status, body, http.StatusOK, "<ID>1</ID>")
+
+	status, body = s.call("", "GetPerson", "<ID>1</ID>", "")
+	expect(t, status, body, http.StatusOK, "<name>Иван</name>", "<version>1</version>")
+
+	status, body = s.call("", "GetPerson", "<ID>2</ID>", "")
+	expect(t, status, body, http.StatusNotFound, "tns:RecordNotFound")
+}
+
+func TestPermissions(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.call("", "AddPerson", validPerson, "")
+	expect(t, status, body, http.StatusUnauthorized)
+
+	status, body = s.call(models.RoleReader, "AddPerson", validPerson, "")
+	expect(t, status, body, http.StatusForbidden, "tns:PermissionDenied")
+
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+	status, body = s.call(models.RoleEditor, "DeletePerson", "<ID>1</ID>", "")
+	expect(t, status, body, http.StatusForbidden, "tns:PermissionDenied")
+
+	status, body = s.call(models.RoleAdmin, "DeletePerson", "<ID>1</ID>", "")
+	expect(t, status, body, http.StatusOK)
+}
+
+func TestValidationFaultListsAllViolations(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.call(models.RoleEditor, "AddPerson", `<Name>Иван</Name><Email>bad</Email><Telephone>123</Telephone>`, "")
+	expect(t, status, body, http.StatusBadRequest,
+		"tns:ValidationFailed",
+		"<field>email</field><rule>format</rule>",
+		"<field>telephone</field><rule>pattern</rule>")
+}
+
+func TestUpdatePersonVersion(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+
+	status, body := s.call(models.RoleEditor, "UpdatePerson", "<ID>1</ID>"+validPerson, "")
+	expect(t, status, body, http.StatusPreconditionRequired, "tns:VersionRequired")
+
+	status, body = s.call(models.RoleEditor, "UpdatePerson", "<ID>1</ID><Version>1</Version>"+validPerson, "")
+	expect(t, status, body, http.StatusOK, "<version>2</version>")
+
+	status, body = s.call(models.RoleEditor, "UpdatePerson", "<ID>1</ID><Version>1</Version>"+validPerson, "")
+	expect(t, status, body, http.StatusPreconditionFailed, "tns:VersionConflict")
+}
+
+func TestUpdatePersonPatch(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+	status, body := s.call(models.RoleEditor, "UpdatePerson",
+		`<ID>1</ID><Version>1</Version><Mode>patch</Mode><Surname>Петров</Surname><Age xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"/>`, "")
+	expect(t, status, body, http.StatusOK, "<version>2</version>")
+
+	person, err := s.storage.PersonRepository.GetPerson(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.Name != "Иван" || person.Surname != "Петров" || person.Age != 0 || person.Email != "ivan@mail.ru" {
+		t.Errorf("unexpected person after patch: %+v", person)
+	}
+}
+
+func TestAddressingRelatesTo(t *testing.T) {
+	s := newTestServer(t)
+	header := `<wsa:MessageID xmlns:wsa="http://www.w3.org/2005/08/addressing">urn:uuid:test-message</wsa:MessageID>`
+	status, body := s.call("", "GetPerson", "<ID>1</ID>", header)
+	expect(t, status, body, http.StatusNotFound, "urn:uuid:test-message</wsa:RelatesTo>", models.WSAddressingFaultAction)
+}
+
+func TestAddressingReplyAddress(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+	replyTo := func(block string, address string) string {
+		return `<wsa:` + block + ` xmlns:wsa="http://www.w3.org/2005/08/addressing"><wsa:Address>` + address + `</wsa:Address></wsa:` + block + `>`
+	}
+	for _, address := range []string{models.WSAddressingAnonymous, models.WSAddressingNone} {
+		status, body := s.call("", "GetPerson", "<ID>1</ID>", replyTo("ReplyTo", address)+replyTo("FaultTo", address))
+		expect(t, status, body, http.StatusOK, "<name>Иван</name>")
+	}
+	status, body := s.call("", "GetPerson", "<ID>1</ID>", replyTo("ReplyTo", "http://example.com/client"))
+	expect(t, status, body, http.StatusBadRequest, "tns:"+models.ErrorOnlyAnonymousAddressSubcode)
+	status, body = s.call("", "GetPerson", "<ID>1</ID>", replyTo("FaultTo", "http://example.com/client"))
+	expect(t, status, body, http.StatusBadRequest, "tns:"+models.ErrorOnlyAnonymousAddressSubcode)
+}
+
+func TestGetPersonAsOfRequiresAudit(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+	request := "<ID>1</ID><AsOf>1</AsOf>"
+
+	status, body := s.call("", "GetPerson", request, "")
+	expect(t, status, body, http.StatusUnauthorized)
+
+	status, body = s.call(models.RoleEditor, "GetPerson", request, "")
+	expect(t, status, body, http.StatusForbidden, "tns:PermissionDenied")
+
+	status, body = s.call(models.RoleAdmin, "GetPerson", request, "")
+	expect(t, status, body, http.StatusOK, "<version>1</version>")
+}
+
+func TestRevertPersonValidatesRevision(t *testing.T) {
+	s := newTestServer(t)
+	//Ревизия 1 сохранена в обход проверки (например, до изменения правил)
+	repo := s.storage.PersonRepository
+	id, err := repo.AddPerson(&models.Person{Name: "Иван", Email: "invalid", Telephone: "+79001234567"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdatePerson(&models.Person{ID: id, Version: 1, Name: "Иван", Email: "ivan@mail.ru", Telephone: "+79001234567"}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := s.call(models.RoleEditor, "RevertPerson", "<ID>1</ID><Revision>1</Revision><Version>2</Version>", "")
+	expect(t, status, body, http.StatusBadRequest, "tns:ValidationFailed", "<field>email</field>")
+
+	person, err := repo.GetPerson(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.Version != 2 || person.Email != "ivan@mail.ru" {
+		t.Errorf("invalid revision was saved: %+v", person)
+	}
+}
+
+func TestUpdatePersonEmptyPatch(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+
+	status, body := s.call(models.RoleEditor, "UpdatePerson", "<ID>1</ID><Version>1</Version><Mode>patch</Mode>", "")
+	expect(t, status, body, http.StatusOK, "<version>1</version>")
+
+	status, body = s.call(models.RoleEditor, "UpdatePerson", "<ID>1</ID><Version>2</Version><Mode>patch</Mode>", "")
+	expect(t, status, body, http.StatusPreconditionFailed, "tns:VersionConflict")
+
+	history, err := s.storage.PersonRepository.PersonHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Errorf("empty patch was recorded in history: %+v", history)
+	}
+}
+
+/*
+Элемент element типа typeName в схеме сервиса
+*/
+func schemaElement(t *testing.T, typeName string, element string) xsdElement {
+	t.Helper()
+	for _, ct := range buildSchema().ComplexTypes {
+		if ct.Name != typeName || ct.Sequence == nil {
+			continue
+		}
+		for _, e := range ct.Sequence.Elements {
+			if e.Name == element {
+				return e
+			}
+		}
+	}
+	t.Fatalf("element %s/%s not found in schema", typeName, element)
+	return xsdElement{}
+}
+
+func TestSchemaAsOfIsOptional(t *testing.T) {
+	if e := schemaElement(t, "GetPersonRequest", "AsOf"); e.MinOccurs != "0" {
+		t.Errorf("GetPersonRequest/AsOf minOccurs = %q, want \"0\"", e.MinOccurs)
+	}
+	if e := schemaElement(t, "GetPersonRequest", "ID"); e.MinOccurs != "" {
+		t.Errorf("GetPersonRequest/ID minOccurs = %q, want required", e.MinOccurs)
+	}
+}
+
+// Элемент Person пакетного запроса с номером n (email и телефон уникальны)
+func batchPerson(n int) string {
+	return fmt.Sprintf(`<Person><Name>Иван</Name><Surname>Иванов</Surname><Age>30</Age><Email>person%d@mail.ru</Email><Telephone>+7900123%04d</Telephone></Person>`, n, n)
+}
+
+func TestAddPersonsAtomicRollback(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.call(models.RoleEditor, "AddPersons", batchPerson(1)+batchPerson(1)+batchPerson(2), "")
+	expect(t, status, body, http.StatusOK,
+		"<Committed>false</Committed><Succeeded>0</Succeeded><Failed>1</Failed>",
+		"<Result><Index>0</Index><Status>rolledBack</Status>",
+		"<Result><Index>1</Index><Status>failed</Status><errorCode>409</errorCode><subcode>EmailExists</subcode>",
+		"<Result><Index>2</Index><Status>rolledBack</Status>")
+
+	page, err := s.storage.PersonRepository.GetAllPersons(database.PageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalCount != 0 {
+		t.Errorf("rolled back batch left %d persons", page.TotalCount)
+	}
+}
+
+func TestAddPersonsBestEffort(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.call(models.RoleEditor, "AddPersons", "<Mode>bestEffort</Mode>"+batchPerson(1)+batchPerson(1)+batchPerson(2), "")
+	expect(t, status, body, http.StatusOK,
+		"<Committed>true</Committed><Succeeded>2</Succeeded><Failed>1</Failed>",
+		"<Result><Index>0</Index><Status>ok</Status><ID>1</ID>",
+		"<Result><Index>1</Index><Status>failed</Status>",
+		"<Result><Index>2</Index><Status>ok</Status>")
+
+	page, err := s.storage.PersonRepository.GetAllPersons(database.PageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalCount != 2 {
+		t.Errorf("persons after best-effort batch = %d, want 2", page.TotalCount)
+	}
+}
+
+func TestUpdatePersonsChecksVersionFirst(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPersons", batchPerson(1)+batchPerson(2), "")
+	//Элемент без версии с некорректным email: как и в UpdatePerson, сначала VersionRequired
+	invalid := "<ID>1</ID><Name>Иван</Name><Email>bad</Email><Telephone>+79001234567</Telephone>"
+	status, body := s.call(models.RoleEditor, "UpdatePersons",
+		"<Mode>bestEffort</Mode><Person>"+invalid+"</Person><Person><ID>2</ID><Version>1</Version><Mode>patch</Mode><Age>40</Age></Person>", "")
+	expect(t, status, body, http.StatusOK,
+		"<Committed>true</Committed><Succeeded>1</Succeeded><Failed>1</Failed>",
+		"<Result><Index>0</Index><Status>failed</Status><errorCode>428</errorCode><subcode>VersionRequired</subcode>",
+		"<Result><Index>1</Index><Status>ok</Status><ID>2</ID>")
+
+	status, body = s.call(models.RoleEditor, "UpdatePerson", invalid, "")
+	expect(t, status, body, http.StatusPreconditionRequired, "tns:VersionRequired")
+}
+
+func TestDeletePersonsBestEffort(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPersons", batchPerson(1)+batchPerson(2), "")
+	status, body := s.call(models.RoleAdmin, "DeletePersons", "<Mode>bestEffort</Mode><ID>1</ID><ID>5</ID><ID>2</ID>", "")
+	expect(t, status, body, http.StatusOK,
+		"<Committed>true</Committed><Succeeded>2</Succeeded><Failed>1</Failed>",
+		"<Result><Index>1</Index><Status>failed</Status><errorCode>404</errorCode>")
+
+	status, body = s.call(models.RoleAdmin, "DeletePersons", "<ID>3</ID>", "")
+	expect(t, status, body, http.StatusOK, "<Committed>false</Committed>")
+}
+
+func TestBatchSizeLimit(t *testing.T) {
+	s := newTestServer(t)
+	status, body := s.call(models.RoleEditor, "AddPersons", "", "")
+	expect(t, status, body, http.StatusBadRequest, "tns:InvalidBatch")
+
+	ids := strings.Repeat("<ID>1</ID>", database.MaxBatchSize)
+	status, body = s.call(models.RoleAdmin, "DeletePersons", "<Mode>bestEffort</Mode>"+ids, "")
+	expect(t, status, body, http.StatusOK, fmt.Sprintf("<Failed>%d</Failed>", database.MaxBatchSize))
+
+	status, body = s.call(models.RoleAdmin, "DeletePersons", ids+"<ID>1</ID>", "")
+	expect(t, status, body, http.StatusBadRequest, "tns:InvalidBatch")
+
+	status, body = s.call(models.RoleEditor, "AddPersons", "<Mode>all</Mode>"+batchPerson(1), "")
+	expect(t, status, body, http.StatusBadRequest, "tns:InvalidBatch")
+}
+
+// Проверка, что документ - корректный XML
+func wellFormed(t *testing.T, document string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("malformed XML: %v", err)
+		}
+	}
+}
+
+func TestWSDL11(t *testing.T) {
+	s := newTestServer(t)
+	status, contentType, body := s.get("wsdl")
+	expect(t, status, body, http.StatusOK,
+		"<wsdl:definitions",
+		`<soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http">`,
+		`<soap12:binding style="document" transport="http://schemas.xmlsoap.org/soap/http">`,
+		`<soap12:address location="http://example.com/soap">`,
+		`<xs:element name="GetPerson" type="tns:GetPersonRequest">`)
+	if !strings.HasPrefix(contentType, "text/xml") {
+		t.Errorf("Content-Type = %q, want text/xml", contentType)
+	}
+	wellFormed(t, body)
+	for _, op := range Operations.All() {
+		for _, fragment := range []string{
+			`<wsdl:operation name="` + op.Name.Local + `">`,
+			`soapAction="` + op.Action + `"`,
+			`<wsdl:message name="` + op.Name.Local + `Request">`,
+		} {
+			if !strings.Contains(body, fragment) {
+				t.Errorf("WSDL does not contain %q", fragment)
+			}
+		}
+	}
+}
+
+func TestWSDL20(t *testing.T) {
+	s := newTestServer(t)
+	status, _, body := s.get("wsdl=2.0")
+	expect(t, status, body, http.StatusOK,
+		"<wsdl:description",
+		`wsoap:version="1.2"`,
+		`<wsdl:endpoint name="PersonServiceSoapEndpoint" binding="tns:PersonServiceSoapBinding" address="http://example.com/soap">`,
+		`<wsdl:operation ref="tns:AddPerson" wsoap:action="http://wst.lab/persons/AddPerson">`)
+	wellFormed(t, body)
+}
+
+func TestXSD(t *testing.T) {
+	s := newTestServer(t)
+	status, _, body := s.get("xsd")
+	expect(t, status, body, http.StatusOK,
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="http://wst.lab/persons" targetNamespace="http://wst.lab/persons" elementFormDefault="qualified">`,
+		`<xs:complexType name="Person">`)
+	wellFormed(t, body)
+
+	status, _, body = s.get("")
+	expect(t, status, body, http.StatusBadRequest)
+}
+
+func TestSchemaOptionalElements(t *testing.T) {
+	//Указатель и omitempty - необязательный элемент, срез - повторяющийся
+	if e := schemaElement(t, "SearchPersonRequest", "Criteria"); e.MinOccurs != "0" || e.Type != "tns:SearchCriteria" {
+		t.Errorf("SearchPersonRequest/Criteria = %+v", e)
+	}
+	if e := schemaElement(t, "DeletePersonsRequest", "ID"); e.MinOccurs != "0" || e.MaxOccurs != "unbounded" || e.Type != "xs:unsignedInt" {
+		t.Errorf("DeletePersonsRequest/ID = %+v", e)
+	}
+	if e := schemaElement(t, "UpdatePersonRequest", "Age"); e.MinOccurs != "0" || e.Nillable != "true" || e.Type != "xs:int" {
+		t.Errorf("UpdatePersonRequest/Age = %+v", e)
+	}
+	if e := schemaElement(t, "AddPersonRequest", "Email"); e.MinOccurs != "" {
+		t.Errorf("AddPersonRequest/Email = %+v, want required", e)
+	}
+}
+
+// Конверт SOAP 1.1 с операцией operation и телом body
+func envelope11(operation string, body string) string {
+	return `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<` + operation + ` xmlns="http://wst.lab/persons">` + body + `</` + operation + `></soap:Body></soap:Envelope>`
+}
+
+func TestSOAP11Request(t *testing.T) {
+	s := newTestServer(t)
+	status, contentType, body := s.post(models.RoleEditor, "text/xml; charset=utf-8", soapActionURI("AddPerson"), envelope11("AddPerson", validPerson))
+	expect(t, status, body, http.StatusOK, `xmlns:env="`+models.SOAP11EnvelopeNamespace+`"`, "<ID>1</ID>")
+	if contentType != models.SOAP11ContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, models.SOAP11ContentType)
+	}
+
+	status, _, body = s.post("", "text/xml", "", envelope11("GetPerson", "<ID>1</ID>"))
+	expect(t, status, body, http.StatusOK, "<name>Иван</name>")
+}
+
+func TestSOAP11Fault(t *testing.T) {
+	s := newTestServer(t)
+	status, contentType, body := s.post("", "text/xml", soapActionURI("GetPerson"), envelope11("GetPerson", "<ID>1</ID>"))
+	expect(t, status, body, http.StatusInternalServerError, "<faultcode>env:Client.RecordNotFound</faultcode>", "<faultstring")
+	if contentType != models.SOAP11ContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, models.SOAP11ContentType)
+	}
+	if strings.Contains(body, "env:Code") {
+		t.Errorf("SOAP 1.1 fault contains SOAP 1.2 elements: %s", body)
+	}
+
+	status, _, body = s.post(models.RoleEditor, "text/xml", "", envelope11("AddPerson", "<Name>Иван</Name>"))
+	expect(t, status, body, http.StatusInternalServerError, "<faultcode>env:Client.ValidationFailed</faultcode>")
+}
+
+func TestBodyElementCount(t *testing.T) {
+	s := newTestServer(t)
+	status, _, body := s.post("", "application/soap+xml", "",
+		`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body></env:Body></env:Envelope>`)
+	expect(t, status, body, http.StatusBadRequest, "tns:EmptyBody")
+
+	status, _, body = s.post("", "application/soap+xml", "",
+		`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body>`+
+			`<GetPerson xmlns="http://wst.lab/persons"><ID>1</ID></GetPerson>`+
+			`<GetPerson xmlns="http://wst.lab/persons"><ID>2</ID></GetPerson>`+
+			`</env:Body></env:Envelope>`)
+	expect(t, status, body, http.StatusBadRequest, "tns:MultipleOperations")
+}
+
+func TestActionMismatch(t *testing.T) {
+	s := newTestServer(t)
+	status, _, body := s.post("", "text/xml", soapActionURI("DeletePerson"), envelope11("GetPerson", "<ID>1</ID>"))
+	expect(t, status, body, http.StatusInternalServerError, "<faultcode>env:Client.ActionMismatch</faultcode>")
+
+	status, _, body = s.post("", `application/soap+xml; action="`+soapActionURI("DeletePerson")+`"`, "",
+		`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body>`+
+			`<GetPerson xmlns="http://wst.lab/persons"><ID>1</ID></GetPerson></env:Body></env:Envelope>`)
+	expect(t, status, body, http.StatusBadRequest, "tns:ActionMismatch")
+
+	header := `<wsa:Action xmlns:wsa="http://www.w3.org/2005/08/addressing">` + soapActionURI("DeletePerson") + `</wsa:Action>`
+	status, body = s.call("", "GetPerson", "<ID>1</ID>", header)
+	expect(t, status, body, http.StatusBadRequest, "tns:ActionMismatch")
+
+	status, body = s.call("", "GetPerson", "<ID>1</ID>", strings.ReplaceAll(header, "DeletePerson", "GetPerson"))
+	expect(t, status, body, http.StatusNotFound, "tns:RecordNotFound")
+}
+
+func TestRankedSearchHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.call(models.RoleEditor, "AddPerson", validPerson, "")
+	status, body := s.call("", "SearchPerson", "<Mode>ranked</Mode><Query>Ivanov</Query>", "")
+	expect(t, status, body, http.StatusOK, "<name>Иван</name>", "<score>")
+
+	status, body = s.call("", "SearchPerson", "<Mode>ranked</Mode><Query>Иванов</Query><SortBy>age</SortBy>", "")
+	expect(t, status, body, http.StatusBadRequest)
+
+	status, body = s.call("", "SearchPerson", "<Mode>ranked</Mode><Criteria><Name><Value>Иван</Value></Name></Criteria>", "")
+	expect(t, status, body, http.StatusBadRequest)
+}
+
+func TestMustUnderstand(t *testing.T) {
+	s := newTestServer(t)
+	header := `<t:Transaction xmlns:t="http://example.com/tx" env:mustUnderstand="true">5</t:Transaction>` +
+		`<t:Trace xmlns:t="http://example.com/trace" env:mustUnderstand="1">on</t:Trace>`
+	status, body := s.call("", "GetPerson", "<ID>1</ID>", header)
+	expect(t, status, body, http.StatusInternalServerError,
+		"<env:Value>env:MustUnderstand</env:Value>",
+		`<env:NotUnderstood qname="h:Transaction" xmlns:h="http://example.com/tx">`,
+		`<env:NotUnderstood qname="h:Trace" xmlns:h="http://example.com/trace">`)
+
+	//Необязательный блок и блок для другого узла игнорируются
+	header = `<t:Transaction xmlns:t="http://example.com/tx">5</t:Transaction>` +
+		`<t:Trace xmlns:t="http://example.com/trace" env:mustUnderstand="1" env:role="http://example.com/other">on</t:Trace>`
+	status, body = s.call("", "GetPerson", "<ID>1</ID>", header)
+	expect(t, status, body, http.StatusNotFound, "tns:RecordNotFound")
+
+	//В SOAP 1.1 блоки NotUnderstood не передаются
+	status, _, body = s.post("", "text/xml", "",
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Header>`+
+			`<t:Transaction xmlns:t="http://example.com/tx" soap:mustUnderstand="1">5</t:Transaction></soap:Header>`+
+			`<soap:Body><GetPerson xmlns="http://wst.lab/persons"><ID>1</ID></GetPerson></soap:Body></soap:Envelope>`)
+	expect(t, status, body, http.StatusInternalServerError, "<faultcode>env:MustUnderstand</faultcode>")
+	if strings.Contains(body, "env:NotUnderstood") {
+		t.Errorf("SOAP 1.1 response contains NotUnderstood: %s", body)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	s := newTestServer(t)
+	status, _, body := s.post("", "text/xml", "",
+		`<env:Envelope xmlns:env="http://example.com/envelope"><env:Body>`+
+			`<GetPerson xmlns="http://wst.lab/persons"><ID>1</ID></GetPerson></env:Body></env:Envelope>`)
+	expect(t, status, body, http.StatusInternalServerError,
+		"<env:Value>env:VersionMismatch</env:Value>", "<errorCode xmlns=\"http://wst.lab/persons\">500</errorCode>")
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"WST_lab1_server_new1/internal/database"
+	"WST_lab1_server_new1/internal/models"
+	"WST_lab1_server_new1/internal/validation"
+	"errors"
+)
+
+var (
+	// Неизвестный режим обновления
+	errInvalidUpdateMode = errors.New("invalid update mode")
+	// Не указана версия обновляемой записи
+	errNoVersion = errors.New("version is required")
+)
+
+/*
+Проверка запроса обновления, общая для UpdatePerson и элементов UpdatePersons:
+сначала версия записи (без нее изменения другого клиента были бы перезаписаны),
+затем режим и поля по правилам
+*/
+func checkUpdate(rules *validation.Rules, request *models.UpdatePersonRequest) error {
+	if request.Version == 0 {
+		return errNoVersion
+	}
+	return validateUpdate(rules, request)
+}
+
+/*
+Проверка запроса обновления по правилам: в режиме replace проверяются все поля,
+в режиме patch - только переданные (поле с xsi:nil проверяется как пустое)
+*/
+func validateUpdate(rules *validation.Rules, request *models.UpdatePersonRequest) error {
+	var fields []string
+	switch request.Mode {
+	case "", models.UpdateModeReplace:
+		fields = validation.Fields
+	case models.UpdateModePatch:
+		fields = suppliedFields(request)
+		if len(fields) == 0 {
+			return nil
+		}
+	default:
+		return errInvalidUpdateMode
+	}
+	person := models.Person{
+		Name:      request.Name.Value,
+		Surname:   request.Surname.Value,
+		Age:       request.Age.Value,
+		Email:     request.Email.Value,
+		Telephone: request.Telephone.Value,
+	}
+	return rules.Check(&person, fields...)
+}
+
+// Поля, переданные в запросе обновления, в порядке validation.Fields
+func suppliedFields(request *models.UpdatePersonRequest) []string {
+	supplied := map[string]bool{
+		"name":      request.Name.Present,
+		"surname":   request.Surname.Present,
+		"age":       request.Age.Present,
+		"email":     request.Email.Present,
+		"telephone": request.Telephone.Present,
+	}
+	var fields []string
+	for _, field := range validation.Fields {
+		if supplied[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Применение поля запроса: в режиме patch - только переданного элемента
+func applyField[T any](field *T, value models.Optional[T], patch bool) {
+	if !patch || value.Present {
+		*field = value.Value
+	}
+}
+
+/*
+Функция обновления записи по запросу. В режиме patch переданные поля применяются
+к текущему состоянию записи в той же транзакции. Возвращает запись с новой версией.
+Пустой patch ничего не изменяет: после проверки версии возвращается текущая запись
+*/
+func updatePerson(repo database.PersonRepository, request *models.UpdatePersonRequest) (*models.Person, error) {
+	patch := request.Mode == models.UpdateModePatch
+	apply := func(tx database.PersonRepository, person *models.Person) error {
+		applyField(&person.Name, request.Name, patch)
+		applyField(&person.Surname, request.Surname, patch)
+		applyField(&person.Age, request.Age, patch)
+		applyField(&person.Email, request.Email, patch)
+		applyField(&person.Telephone, request.Telephone, patch)
+		person.Version = request.Version
+		return tx.UpdatePerson(person)
+	}
+	if !patch {
+		person := &models.Person{ID: request.ID}
+		return person, apply(repo, person)
+	}
+	var person *models.Person
+	err := repo.Transaction(func(tx database.PersonRepository) error {
+		current, err := tx.GetPerson(request.ID)
+		if err != nil {
+			return err
+		}
+		person = current
+		if len(suppliedFields(request)) == 0 {
+			if current.Version != request.Version {
+				return database.ErrVersionConflict
+			}
+			return nil
+		}
+		return apply(tx, person)
+	})
+	return person, err
+}
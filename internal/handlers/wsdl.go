@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"WST_lab1_server_new1/internal/models"
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	nsXSD          = "http://www.w3.org/2001/XMLSchema"
+	nsWSDL11       = "http://schemas.xmlsoap.org/wsdl/"
+	nsWSDL11SOAP   = "http://schemas.xmlsoap.org/wsdl/soap/"
+	nsWSDL11SOAP12 = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	nsWSDL20       = "http://www.w3.org/ns/wsdl"
+	nsWSDL20SOAP   = "http://www.w3.org/ns/wsdl/soap"
+	httpTransport  = "http://schemas.xmlsoap.org/soap/http"
+	serviceName    = "PersonService"
+)
+
+/*
+Структуры XML Schema
+*/
+type xsdSchema struct {
+	XMLName            xml.Name         `xml:"xs:schema"`
+	XmlnsXS            string           `xml:"xmlns:xs,attr"`
+	XmlnsTns           string           `xml:"xmlns:tns,attr"`
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Elements           []xsdElement     `xml:"xs:element"`
+	ComplexTypes       []xsdComplexType `xml:"xs:complexType"`
+}
+
+type xsdElement struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	MinOccurs string `xml:"minOccurs,attr,omitempty"`
+	MaxOccurs string `xml:"maxOccurs,attr,omitempty"`
+	Nillable  string `xml:"nillable,attr,omitempty"`
+}
+
+type xsdAttribute struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+	Use  string `xml:"use,attr,omitempty"`
+}
+
+type xsdComplexType struct {
+	Name       string         `xml:"name,attr"`
+	Sequence   *xsdSequence   `xml:"xs:sequence,omitempty"`
+	Attributes []xsdAttribute `xml:"xs:attribute"`
+}
+
+type xsdSequence struct {
+	Elements []xsdElement `xml:"xs:element"`
+}
+
+/*
+Структуры WSDL 1.1
+*/
+type wsdl11Definitions struct {
+	XMLName         xml.Name        `xml:"wsdl:definitions"`
+	XmlnsWSDL       string          `xml:"xmlns:wsdl,attr"`
+	XmlnsSOAP       string          `xml:"xmlns:soap,attr"`
+	XmlnsSOAP12     string          `xml:"xmlns:soap12,attr"`
+	XmlnsXS         string          `xml:"xmlns:xs,attr"`
+	XmlnsTns        string          `xml:"xmlns:tns,attr"`
+	Name            string          `xml:"name,attr"`
+	TargetNamespace string          `xml:"targetNamespace,attr"`
+	Schema          xsdSchema       `xml:"wsdl:types>xs:schema"`
+	Messages        []wsdl11Message `xml:"wsdl:message"`
+	PortType        wsdl11PortType  `xml:"wsdl:portType"`
+	Bindings        []wsdl11Binding `xml:"wsdl:binding"`
+	Service         wsdl11Service   `xml:"wsdl:service"`
+}
+
+type wsdl11Message struct {
+	Name string     `xml:"name,attr"`
+	Part wsdl11Part `xml:"wsdl:part"`
+}
+
+type wsdl11Part struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+}
+
+type wsdl11PortType struct {
+	Name       string                    `xml:"name,attr"`
+	Operations []wsdl11PortTypeOperation `xml:"wsdl:operation"`
+}
+
+type wsdl11PortTypeOperation struct {
+	Name   string           `xml:"name,attr"`
+	Input  wsdl11MessageRef `xml:"wsdl:input"`
+	Output wsdl11MessageRef `xml:"wsdl:output"`
+}
+
+type wsdl11MessageRef struct {
+	Message string `xml:"message,attr"`
+}
+
+type wsdl11Binding struct {
+	Name        string                   `xml:"name,attr"`
+	Type        string                   `xml:"type,attr"`
+	SOAPBinding wsdl11SOAPBinding        `xml:"binding"`
+	Operations  []wsdl11BindingOperation `xml:"wsdl:operation"`
+}
+
+/*
+Элементы расширения SOAP: имя (soap:... или soap12:...) задается через XMLName
+в зависимости от версии привязки
+*/
+type wsdl11SOAPBinding struct {
+	XMLName   xml.Name
+	Style     string `xml:"style,attr"`
+	Transport string `xml:"transport,attr"`
+}
+
+type wsdl11BindingOperation struct {
+	Name          string              `xml:"name,attr"`
+	SOAPOperation wsdl11SOAPOperation `xml:"operation"`
+	Input         wsdl11SOAPBody      `xml:"wsdl:input>body"`
+	Output        wsdl11SOAPBody      `xml:"wsdl:output>body"`
+}
+
+type wsdl11SOAPOperation struct {
+	XMLName    xml.Name
+	SOAPAction string `xml:"soapAction,attr"`
+}
+
+type wsdl11SOAPBody struct {
+	XMLName xml.Name
+	Use     string `xml:"use,attr"`
+}
+
+type wsdl11Service struct {
+	Name  string       `xml:"name,attr"`
+	Ports []wsdl11Port `xml:"wsdl:port"`
+}
+
+type wsdl11Port struct {
+	Name    string            `xml:"name,attr"`
+	Binding string            `xml:"binding,attr"`
+	Address wsdl11SOAPAddress `xml:"address"`
+}
+
+type wsdl11SOAPAddress struct {
+	XMLName  xml.Name
+	Location string `xml:"location,attr"`
+}
+
+/*
+Структуры WSDL 2.0
+*/
+type wsdl20Description struct {
+	XMLName         xml.Name        `xml:"wsdl:description"`
+	XmlnsWSDL       string          `xml:"xmlns:wsdl,attr"`
+	XmlnsWSOAP      string          `xml:"xmlns:wsoap,attr"`
+	XmlnsXS         string          `xml:"xmlns:xs,attr"`
+	XmlnsTns        string          `xml:"xmlns:tns,attr"`
+	TargetNamespace string          `xml:"targetNamespace,attr"`
+	Schema          xsdSchema       `xml:"wsdl:types>xs:schema"`
+	Interface       wsdl20Interface `xml:"wsdl:interface"`
+	Binding         wsdl20Binding   `xml:"wsdl:binding"`
+	Service         wsdl20Service   `xml:"wsdl:service"`
+}
+
+type wsdl20Interface struct {
+	Name       string                     `xml:"name,attr"`
+	Operations []wsdl20InterfaceOperation `xml:"wsdl:operation"`
+}
+
+type wsdl20InterfaceOperation struct {
+	Name    string           `xml:"name,attr"`
+	Pattern string           `xml:"pattern,attr"`
+	Input   wsdl20ElementRef `xml:"wsdl:input"`
+	Output  wsdl20ElementRef `xml:"wsdl:output"`
+}
+
+type wsdl20ElementRef struct {
+	Element string `xml:"element,attr"`
+}
+
+type wsdl20Binding struct {
+	Name       string                   `xml:"name,attr"`
+	Interface  string                   `xml:"interface,attr"`
+	Type       string                   `xml:"type,attr"`
+	Version    string                   `xml:"wsoap:version,attr"`
+	Protocol   string                   `xml:"wsoap:protocol,attr"`
+	Operations []wsdl20BindingOperation `xml:"wsdl:operation"`
+}
+
+type wsdl20BindingOperation struct {
+	Ref    string `xml:"ref,attr"`
+	Action string `xml:"wsoap:action,attr"`
+}
+
+type wsdl20Service struct {
+	Name      string         `xml:"name,attr"`
+	Interface string         `xml:"interface,attr"`
+	Endpoint  wsdl20Endpoint `xml:"wsdl:endpoint"`
+}
+
+type wsdl20Endpoint struct {
+	Name    string `xml:"name,attr"`
+	Binding string `xml:"binding,attr"`
+	Address string `xml:"address,attr"`
+}
+
+/*
+Построитель XML Schema по Go структурам операций
+*/
+type schemaBuilder struct {
+	schema xsdSchema
+	seen   map[reflect.Type]bool
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schema: xsdSchema{
+			XmlnsXS:            nsXSD,
+			XmlnsTns:           models.ServiceNamespace,
+			TargetNamespace:    models.ServiceNamespace,
+			ElementFormDefault: "qualified",
+		},
+		seen: map[reflect.Type]bool{},
+	}
+}
+
+// Добавляет глобальный элемент с типом, построенным по значению v
+func (b *schemaBuilder) addElement(name string, v any) {
+	t := reflect.TypeOf(v)
+	b.schema.Elements = append(b.schema.Elements, xsdElement{Name: name, Type: b.typeName(t)})
+}
+
+// Возвращает имя XSD типа для Go типа, при необходимости регистрируя complexType
+func (b *schemaBuilder) typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "xs:dateTime"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "xs:string"
+	case reflect.Bool:
+		return "xs:boolean"
+	case reflect.Int, reflect.Int32:
+		return "xs:int"
+	case reflect.Int64:
+		return "xs:long"
+	case reflect.Uint, reflect.Uint32:
+		return "xs:unsignedInt"
+	case reflect.Uint64:
+		return "xs:unsignedLong"
+	case reflect.Float32:
+		return "xs:float"
+	case reflect.Float64:
+		return "xs:double"
+	case reflect.Struct:
+		b.addComplexType(t)
+		return "tns:" + t.Name()
+	}
+	return "xs:string"
+}
+
+// Регистрирует complexType по полям структуры с учетом xml тегов
+func (b *schemaBuilder) addComplexType(t reflect.Type) {
+	if b.seen[t] {
+		return
+	}
+	b.seen[t] = true
+	ct := xsdComplexType{Name: t.Name()}
+	var elements []xsdElement
+	b.collectFields(t, &elements, &ct.Attributes)
+	if len(elements) > 0 {
+		ct.Sequence = &xsdSequence{Elements: elements}
+	}
+	b.schema.ComplexTypes = append(b.schema.ComplexTypes, ct)
+}
+
+func (b *schemaBuilder) collectFields(t reflect.Type, elements *[]xsdElement, attributes *[]xsdAttribute) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Name == "XMLName" {
+			continue
+		}
+		tag := field.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.collectFields(ft, elements, attributes)
+				continue
+			}
+		}
+		// Оставляем локальное имя, если в теге указано пространство имен
+		if i := strings.LastIndex(name, " "); i >= 0 {
+			name = name[i+1:]
+		}
+		if name == "" {
+			name = field.Name
+		}
+		optional := strings.Contains(opts, "omitempty")
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			optional = true
+			ft = ft.Elem()
+		}
+		if strings.Contains(opts, "attr") {
+			attr := xsdAttribute{Name: name, Type: b.typeName(ft)}
+			if !optional {
+				attr.Use = "required"
+			}
+			*attributes = append(*attributes, attr)
+			continue
+		}
+		element := xsdElement{Name: name}
+		// Поле models.Optional: необязательный элемент, допускающий xsi:nil
+		if value, ok := reflect.Zero(ft).Interface().(interface{ ValueType() reflect.Type }); ok {
+			element.Type = b.typeName(value.ValueType())
+			element.MinOccurs = "0"
+			element.Nillable = "true"
+		} else if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			element.Type = b.typeName(ft.Elem())
+			element.MinOccurs = "0"
+			element.MaxOccurs = "unbounded"
+		} else {
+			element.Type = b.typeName(ft)
+			if optional {
+				element.MinOccurs = "0"
+			}
+		}
+		*elements = append(*elements, element)
+	}
+}
+
+// Строит XML Schema по зарегистрированным операциям
+func buildSchema() xsdSchema {
+	b := newSchemaBuilder()
+	for _, op := range Operations.All() {
+		b.addElement(op.Name.Local, op.Request)
+		b.addElement(op.Name.Local+"Response", op.Response)
+	}
+	return b.schema
+}
+
+// Формирует WSDL 1.1 с привязками SOAP 1.1 и SOAP 1.2
+func buildWSDL11(location string) wsdl11Definitions {
+	defs := wsdl11Definitions{
+		XmlnsWSDL:       nsWSDL11,
+		XmlnsSOAP:       nsWSDL11SOAP,
+		XmlnsSOAP12:     nsWSDL11SOAP12,
+		XmlnsXS:         nsXSD,
+		XmlnsTns:        models.ServiceNamespace,
+		Name:            serviceName,
+		TargetNamespace: models.ServiceNamespace,
+		Schema:          buildSchema(),
+		PortType:        wsdl11PortType{Name: serviceName + "PortType"},
+		Service:         wsdl11Service{Name: serviceName},
+	}
+	for _, op := range Operations.All() {
+		input, output := op.Name.Local+"Request", op.Name.Local+"Response"
+		defs.Messages = append(defs.Messages,
+			wsdl11Message{Name: input, Part: wsdl11Part{Name: "parameters", Element: "tns:" + op.Name.Local}},
+			wsdl11Message{Name: output, Part: wsdl11Part{Name: "parameters", Element: "tns:" + op.Name.Local + "Response"}},
+		)
+		defs.PortType.Operations = append(defs.PortType.Operations, wsdl11PortTypeOperation{
+			Name:   op.Name.Local,
+			Input:  wsdl11MessageRef{Message: "tns:" + input},
+			Output: wsdl11MessageRef{Message: "tns:" + output},
+		})
+	}
+	for _, binding := range []struct{ prefix, name string }{
+		{"soap", serviceName + "SoapBinding"},
+		{"soap12", serviceName + "Soap12Binding"},
+	} {
+		b := wsdl11Binding{
+			Name: binding.name,
+			Type: "tns:" + serviceName + "PortType",
+			SOAPBinding: wsdl11SOAPBinding{
+				XMLName:   xml.Name{Local: binding.prefix + ":binding"},
+				Style:     "document",
+				Transport: httpTransport,
+			},
+		}
+		body := wsdl11SOAPBody{XMLName: xml.Name{Local: binding.prefix + ":body"}, Use: "literal"}
+		for _, op := range Operations.All() {
+			b.Operations = append(b.Operations, wsdl11BindingOperation{
+				Name: op.Name.Local,
+				SOAPOperation: wsdl11SOAPOperation{
+					XMLName:    xml.Name{Local: binding.prefix + ":operation"},
+					SOAPAction: op.Action,
+				},
+				Input:  body,
+				Output: body,
+			})
+		}
+		defs.Bindings = append(defs.Bindings, b)
+		defs.Service.Ports = append(defs.Service.Ports, wsdl11Port{
+			Name:    strings.TrimSuffix(binding.name, "Binding") + "Port",
+			Binding: "tns:" + binding.name,
+			Address: wsdl11SOAPAddress{XMLName: xml.Name{Local: binding.prefix + ":address"}, Location: location},
+		})
+	}
+	return defs
+}
+
+// Формирует WSDL 2.0 с SOAP 1.2 привязкой
+func buildWSDL20(location string) wsdl20Description {
+	desc := wsdl20Description{
+		XmlnsWSDL:       nsWSDL20,
+		XmlnsWSOAP:      nsWSDL20SOAP,
+		XmlnsXS:         nsXSD,
+		XmlnsTns:        models.ServiceNamespace,
+		TargetNamespace: models.ServiceNamespace,
+		Schema:          buildSchema(),
+		Interface:       wsdl20Interface{Name: serviceName + "Interface"},
+		Binding: wsdl20Binding{
+			Name:      serviceName + "SoapBinding",
+			Interface: "tns:" + serviceName + "Interface",
+			Type:      nsWSDL20SOAP,
+			Version:   "1.2",
+			Protocol:  "http://www.w3.org/2003/05/soap/bindings/HTTP/",
+		},
+		Service: wsdl20Service{
+			Name:      serviceName,
+			Interface: "tns:" + serviceName + "Interface",
+			Endpoint: wsdl20Endpoint{
+				Name:    serviceName + "SoapEndpoint",
+				Binding: "tns:" + serviceName + "SoapBinding",
+				Address: location,
+			},
+		},
+	}
+	for _, op := range Operations.All() {
+		desc.Interface.Operations = append(desc.Interface.Operations, wsdl20InterfaceOperation{
+			Name:    op.Name.Local,
+			Pattern: "http://www.w3.org/ns/wsdl/in-out",
+			Input:   wsdl20ElementRef{Element: "tns:" + op.Name.Local},
+			Output:  wsdl20ElementRef{Element: "tns:" + op.Name.Local + "Response"},
+		})
+		desc.Binding.Operations = append(desc.Binding.Operations, wsdl20BindingOperation{
+			Ref:    "tns:" + op.Name.Local,
+			Action: op.Action,
+		})
+	}
+	return desc
+}
+
+// Обработчик GET /soap?wsdl, /soap?wsdl=2.0 и /soap?xsd
+func (h *StorageHandler) WSDLHandler(c *gin.Context) {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	location := scheme + "://" + c.Request.Host + c.Request.URL.Path
+
+	var document any
+	if _, ok := c.GetQuery("xsd"); ok {
+		document = buildSchema()
+	} else if version, ok := c.GetQuery("wsdl"); ok {
+		if version == "2.0" || version == "2" {
+			document = buildWSDL20(location)
+		} else {
+			document = buildWSDL11(location)
+		}
+	} else {
+		c.String(http.StatusBadRequest, "Use ?wsdl, ?wsdl=2.0 or ?xsd")
+		return
+	}
+
+	out, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error generating WSDL")
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"WST_lab1_server_new1/internal/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Ключ контекста gin с логгером запроса; обработчик SOAP добавляет в него MessageID WS-Addressing
+const LoggerKey = "logger"
+
+/*
+Журнал HTTP запросов через zap вместо стандартного логгера gin. Запись делается
+после обработки запроса логгером запроса, поэтому содержит его MessageID
+*/
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		logger := logging.Logger
+		if v, ok := c.Get(LoggerKey); ok {
+			logger = v.(*zap.Logger)
+		}
+		logger.Info("HTTP request",
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("clientIP", c.ClientIP()),
+		)
+	}
+}
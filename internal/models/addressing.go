@@ -0,0 +1,31 @@
+package models
+
+import "encoding/xml"
+
+// Адреса и действие Fault WS-Addressing 1.0
+const (
+	WSAddressingAnonymous   = "http://www.w3.org/2005/08/addressing/anonymous"
+	WSAddressingNone        = "http://www.w3.org/2005/08/addressing/none"
+	WSAddressingFaultAction = "http://www.w3.org/2005/08/addressing/soap/fault"
+)
+
+/*
+Ссылка на конечную точку (wsa:ReplyTo, wsa:FaultTo): используется только адрес
+*/
+type EndpointReference struct {
+	Address string `xml:"http://www.w3.org/2005/08/addressing Address"`
+}
+
+/*
+Блок WS-Addressing заголовка ответа со строковым значением
+(wsa:Action, wsa:MessageID, wsa:RelatesTo)
+*/
+type AddressingHeader struct {
+	XMLName xml.Name
+	Xmlns   string `xml:"xmlns:wsa,attr"`
+	Value   string `xml:",chardata"`
+}
+
+func NewAddressingHeader(name string, value string) AddressingHeader {
+	return AddressingHeader{XMLName: xml.Name{Local: "wsa:" + name}, Xmlns: WSAddressingNamespace, Value: value}
+}
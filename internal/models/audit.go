@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Операции, записываемые в журнал изменений
+const (
+	AuditAdd     = "add"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+/*
+Запись журнала изменений Person: операция, автор, адрес клиента,
+MessageID запроса, значения измененных полей до и после операции
+и версия (номер ревизии) записи после операции
+*/
+type AuditEntry struct {
+	ID        uint          `gorm:"primaryKey" xml:"id"`
+	PersonID  uint          `gorm:"not null" xml:"personId"`
+	Operation string        `gorm:"type:varchar(20); not null" xml:"operation"`
+	Username  string        `gorm:"type:varchar(100); not null" xml:"username"`
+	ClientIP  string        `gorm:"type:varchar(64); not null" xml:"clientIp"`
+	MessageID string        `gorm:"type:varchar(200); not null" xml:"messageId,omitempty"`
+	Changes   []FieldChange `gorm:"serializer:json; not null" xml:"Change"`
+	Version   uint          `gorm:"not null" xml:"version"`
+	CreatedAt time.Time     `xml:"timestamp"`
+}
+
+func (AuditEntry) TableName() string {
+	return "person_audit"
+}
+
+/*
+Изменение поля: значения до и после операции (пусто - поле отсутствовало)
+*/
+type FieldChange struct {
+	Field  string `json:"field" xml:"field"`
+	Before string `json:"before,omitempty" xml:"before"`
+	After  string `json:"after,omitempty" xml:"after"`
+}
@@ -1,34 +1,189 @@
 package models
 
-type SOAPFault struct {
-	Envelope struct {
-		Body struct {
-			Fault struct {
-				Code    string `xml:"faultcode"`
-				Message string `xml:"faultstring"`
-				Detail  struct {
-					ErrorCode    string `xml:"errorCode"`
-					ErrorMessage string `xml:"errorMessage"`
-				} `xml:"detail"`
-			} `xml:"Fault"`
-		} `xml:"Body"`
-	} `xml:"Envelope"`
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Коды SOAP 1.2 Fault (env:Code/env:Value)
+const (
+	FaultCodeSender          = "env:Sender"
+	FaultCodeReceiver        = "env:Receiver"
+	FaultCodeVersionMismatch = "env:VersionMismatch"
+	FaultCodeMustUnderstand  = "env:MustUnderstand"
+)
+
+/*
+SOAP 1.2 Fault, сериализуется внутри env:Body конверта ответа
+*/
+type Fault struct {
+	XMLName  xml.Name     `xml:"env:Fault"`
+	XmlnsTns string       `xml:"xmlns:tns,attr,omitempty"`
+	Code     FaultCode    `xml:"env:Code"`
+	Reason   FaultReason  `xml:"env:Reason"`
+	Detail   *FaultDetail `xml:"env:Detail,omitempty"`
+}
+
+type FaultCode struct {
+	Value   string     `xml:"env:Value"`
+	Subcode *FaultCode `xml:"env:Subcode,omitempty"`
+}
+
+type FaultReason struct {
+	Text FaultText `xml:"env:Text"`
+}
+
+type FaultText struct {
+	Lang  string `xml:"xml:lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+type FaultDetail struct {
+	ErrorCode    string      `xml:"http://wst.lab/persons errorCode"`
+	ErrorMessage string      `xml:"http://wst.lab/persons errorMessage"`
+	Violations   []Violation `xml:"http://wst.lab/persons Violation,omitempty"`
+}
+
+/*
+Нарушение правила проверки поля: имя поля, правило и описание
+*/
+type Violation struct {
+	Field   string `xml:"field"`
+	Rule    string `xml:"rule"`
+	Message string `xml:"message"`
+}
+
+/*
+SOAP 1.1 Fault (faultcode/faultstring/detail)
+*/
+type Fault11 struct {
+	XMLName     xml.Name     `xml:"env:Fault"`
+	FaultCode   string       `xml:"faultcode"`
+	FaultString FaultText    `xml:"faultstring"`
+	Detail      *FaultDetail `xml:"detail,omitempty"`
+}
+
+// Коды SOAP 1.2 и соответствующие им коды SOAP 1.1
+var faultCodes11 = map[string]string{
+	FaultCodeSender:          "env:Client",
+	FaultCodeReceiver:        "env:Server",
+	FaultCodeVersionMismatch: "env:VersionMismatch",
+	FaultCodeMustUnderstand:  "env:MustUnderstand",
+}
+
+// Преобразует Fault в представление SOAP 1.1; подкод добавляется через точку (env:Client.EmailExists)
+func (f Fault) SOAP11() Fault11 {
+	code := faultCodes11[f.Code.Value]
+	if code == "" {
+		code = f.Code.Value
+	}
+	if f.Code.Subcode != nil {
+		_, local, found := strings.Cut(f.Code.Subcode.Value, ":")
+		if !found {
+			local = f.Code.Subcode.Value
+		}
+		code += "." + local
+	}
+	return Fault11{
+		FaultCode:   code,
+		FaultString: f.Reason.Text,
+		Detail:      f.Detail,
+	}
 }
 
 const (
+	ErrorInternalCode                = "500"
+	ErrorInternalSubcode             = "InternalError"
+	ErrorInternalMessage             = "Внутренняя ошибка сервера"
+	ErrorInternalDetail              = "Произошла непредвиденная ошибка."
+	ErrorInvalidRequestCode          = "400"
+	ErrorInvalidRequestSubcode       = "InvalidRequest"
+	ErrorInvalidRequestMessage       = "Некорректный запрос"
+	ErrorInvalidRequestDetail        = "Не удалось разобрать SOAP конверт"
+	ErrorUnsupportedOperationCode    = "400"
+	ErrorUnsupportedOperationSubcode = "UnsupportedOperation"
+	ErrorUnsupportedOperationMessage = "Неподдерживаемая операция"
+	ErrorUnsupportedOperationDetail  = "Тело запроса не содержит поддерживаемой операции"
+	ErrorEmptyBodyCode               = "400"
+	ErrorEmptyBodySubcode            = "EmptyBody"
+	ErrorEmptyBodyMessage            = "Пустой запрос"
+	ErrorEmptyBodyDetail             = "Тело SOAP конверта не содержит элемента операции"
+	ErrorMultipleOperationsCode      = "400"
+	ErrorMultipleOperationsSubcode   = "MultipleOperations"
+	ErrorMultipleOperationsMessage   = "Несколько операций в запросе"
+	ErrorMultipleOperationsDetail    = "Тело SOAP конверта должно содержать ровно один элемент операции"
+	ErrorActionMismatchCode          = "400"
+	ErrorActionMismatchSubcode       = "ActionMismatch"
+	ErrorActionMismatchMessage       = "Действие не соответствует операции"
+	ErrorActionMismatchDetail        = "SOAPAction или wsa:Action не соответствует элементу операции в теле запроса"
+	ErrorVersionMismatchCode         = "500"
+	ErrorVersionMismatchMessage      = "Неподдерживаемая версия SOAP"
+	ErrorVersionMismatchDetail       = "Ожидается конверт SOAP 1.1 или SOAP 1.2"
+	ErrorMustUnderstandCode          = "500"
+	ErrorMustUnderstandMessage       = "Обязательный заголовок не поддерживается"
+	ErrorMustUnderstandDetail        = "Блок заголовка с mustUnderstand не может быть обработан сервисом; см. NotUnderstood"
+	ErrorInvalidPagingCode           = "400"
+	ErrorInvalidPagingSubcode        = "InvalidPaging"
+	ErrorInvalidPagingMessage        = "Некорректные параметры страницы"
+	ErrorInvalidPagingDetail         = "Неизвестное поле или направление сортировки либо недействительный PageToken"
+	ErrorEmptyQueryCode              = "400"
+	ErrorEmptyQuerySubcode           = "EmptyQuery"
+	ErrorEmptyQueryMessage           = "Пустой поисковый запрос"
+	ErrorEmptyQueryDetail            = "Укажите Query или хотя бы один критерий Criteria с непустым значением"
+	ErrorQueryTooLongCode            = "400"
+	ErrorQueryTooLongSubcode         = "QueryTooLong"
+	ErrorQueryTooLongMessage         = "Слишком длинный поисковый запрос"
+	ErrorQueryTooLongDetail          = "Значение условия поиска не должно превышать 200 символов"
+	ErrorInvalidSearchCode           = "400"
+	ErrorInvalidSearchSubcode        = "InvalidSearchCriteria"
+	ErrorInvalidSearchMessage        = "Некорректные критерии поиска"
+	ErrorInvalidSearchDetail         = "Допустимы Mode exact/ranked, Match all/any, Op equals/prefix/contains, Min не больше Max; Query и Criteria не указываются вместе"
 	ErrorRecordNotFoundCode          = "404"
+	ErrorRecordNotFoundSubcode       = "RecordNotFound"
 	ErrorRecordNotFoundMessage       = "Запись не найдена"
 	ErrorRecordNotFoundDetail        = "Запрашиваемая запись отсутствует в базе данных."
 	ErrorRecordEmailExistsCode       = "409"
+	ErrorRecordEmailExistsSubcode    = "EmailExists"
 	ErrorRecordEmailExistsMessage    = "Запись уже существует"
 	ErrorRecordEmailExistsDetail     = "Запись с данным email уже существует"
-	ErrorEmailIncorrectCode          = "409"
-	ErrorEmailIncorrectMessage       = "Некорректный email"
-	ErrorEmailIncorrectDetail        = "Получен некорректный email"
-	ErrorPhoneNumberIncorrectCode    = "409"
-	ErrorPhoneNumberIncorrectMessage = "Некорректный phone number"
-	ErrorPhoneNumberIncorrectDetail  = "Получен некорректный phone number"
 	ErrorAuthIncorrectCode           = "401"
+	ErrorAuthIncorrectSubcode        = "AuthenticationFailed"
 	ErrorAuthIncorrectMessage        = "Неудачная Аутентификация"
 	ErrorAuthIncorrectDetail         = "Введен некорректный логин или пароль"
+	ErrorPermissionDeniedCode        = "403"
+	ErrorPermissionDeniedSubcode     = "PermissionDenied"
+	ErrorPermissionDeniedMessage     = "Недостаточно прав"
+	ErrorPermissionDeniedDetail      = "Роль пользователя не позволяет выполнить операцию"
+	ErrorOnlyAnonymousAddressCode    = "400"
+	ErrorOnlyAnonymousAddressSubcode = "OnlyAnonymousAddressSupported"
+	ErrorOnlyAnonymousAddressMessage = "Адрес ответа не поддерживается"
+	ErrorOnlyAnonymousAddressDetail  = "Ответ передается только в том же HTTP соединении: wsa:ReplyTo и wsa:FaultTo должны быть анонимными или none"
+	ErrorInvalidBatchCode            = "400"
+	ErrorInvalidBatchSubcode         = "InvalidBatch"
+	ErrorInvalidBatchMessage         = "Некорректный пакет"
+	ErrorInvalidBatchDetail          = "Пакет должен содержать от 1 до 1000 элементов, Mode - atomic или bestEffort"
+	ErrorVersionConflictCode         = "412"
+	ErrorVersionConflictSubcode      = "VersionConflict"
+	ErrorVersionConflictMessage      = "Запись изменена другим запросом"
+	ErrorVersionConflictDetail       = "Версия записи устарела: получите запись заново и повторите изменение"
+	ErrorVersionRequiredCode         = "428"
+	ErrorVersionRequiredSubcode      = "VersionRequired"
+	ErrorVersionRequiredMessage      = "Не указана версия записи"
+	ErrorVersionRequiredDetail       = "Для обновления укажите Version, полученную из GetPerson"
+	ErrorInvalidUpdateModeCode       = "400"
+	ErrorInvalidUpdateModeSubcode    = "InvalidUpdateMode"
+	ErrorInvalidUpdateModeMessage    = "Некорректный режим обновления"
+	ErrorInvalidUpdateModeDetail     = "Mode должен быть replace (замена всех полей) или patch (только переданные поля)"
+	ErrorValidationFailedCode        = "400"
+	ErrorValidationFailedSubcode     = "ValidationFailed"
+	ErrorValidationFailedMessage     = "Данные не прошли проверку"
+	ErrorValidationFailedDetail      = "Нарушенные правила перечислены в элементах Violation"
+	ErrorInvalidAsOfCode             = "400"
+	ErrorInvalidAsOfSubcode          = "InvalidAsOf"
+	ErrorInvalidAsOfMessage          = "Некорректный момент чтения"
+	ErrorInvalidAsOfDetail           = "AsOf должен быть номером ревизии или временем в формате RFC 3339"
+	ErrorRevisionNotFoundCode        = "404"
+	ErrorRevisionNotFoundSubcode     = "RevisionNotFound"
+	ErrorRevisionNotFoundMessage     = "Ревизия не найдена"
+	ErrorRevisionNotFoundDetail      = "Запрашиваемая ревизия записи отсутствует в журнале изменений"
 )
@@ -2,20 +2,242 @@ package models
 
 import (
 	"encoding/xml"
+	"io"
+	"strings"
 )
 
+// Целевое пространство имен сервиса (WSDL/XSD и элементы операций)
+const ServiceNamespace = "http://wst.lab/persons"
 
+// Пространства имен конвертов SOAP 1.1/1.2 и типы содержимого ответа
+const (
+	SOAP12EnvelopeNamespace = "http://www.w3.org/2003/05/soap-envelope"
+	SOAP12ContentType       = "application/soap+xml; charset=utf-8"
+	SOAP11EnvelopeNamespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	SOAP11ContentType       = "text/xml; charset=utf-8"
+)
+
+// Версия SOAP, определяемая по пространству имен конверта запроса
+type SOAPVersion int
+
+const (
+	SOAP12 SOAPVersion = iota
+	SOAP11
+)
+
+// Определяет версию по пространству имен Envelope
+func SOAPVersionByNamespace(namespace string) (SOAPVersion, bool) {
+	switch namespace {
+	case SOAP12EnvelopeNamespace:
+		return SOAP12, true
+	case SOAP11EnvelopeNamespace:
+		return SOAP11, true
+	}
+	return SOAP12, false
+}
+
+func (v SOAPVersion) Namespace() string {
+	if v == SOAP11 {
+		return SOAP11EnvelopeNamespace
+	}
+	return SOAP12EnvelopeNamespace
+}
+
+func (v SOAPVersion) ContentType() string {
+	if v == SOAP11 {
+		return SOAP11ContentType
+	}
+	return SOAP12ContentType
+}
+
+func (v SOAPVersion) String() string {
+	if v == SOAP11 {
+		return "1.1"
+	}
+	return "1.2"
+}
+
+// Пространство имен Envelope проверяется обработчиком (SOAP 1.1 или 1.2)
 type Envelope struct {
-	XMLName xml.Name `xml:"http://www.w3.org/2003/05/soap-envelope Envelope"`
+	XMLName xml.Name `xml:"Envelope"`
 	Header  Header   `xml:"Header"`
 	Body    Body     `xml:"Body"`
 }
 
+// Пространство имен WS-Addressing
+const WSAddressingNamespace = "http://www.w3.org/2005/08/addressing"
+
+// Роли узла SOAP 1.2 и actor SOAP 1.1, адресующие блок заголовка
+const (
+	SOAP12RoleNext             = "http://www.w3.org/2003/05/soap-envelope/role/next"
+	SOAP12RoleUltimateReceiver = "http://www.w3.org/2003/05/soap-envelope/role/ultimateReceiver"
+	SOAP11ActorNext            = "http://schemas.xmlsoap.org/soap/actor/next"
+)
 
+/*
+Заголовок запроса: блоки сохраняются целиком и разбираются
+обработчиками заголовков, зарегистрированными по имени блока
+*/
 type Header struct {
+	Blocks []Element
+}
+
+func (h *Header) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	blocks, err := readElements(d)
+	if err != nil {
+		return err
+	}
+	h.Blocks = blocks
+	return nil
+}
+
+/*
+Конверт ответа: содержимое Body сериализуется по XMLName элемента ответа операции
+*/
+type ResponseEnvelope struct {
+	XMLName  xml.Name        `xml:"env:Envelope"`
+	XmlnsEnv string          `xml:"xmlns:env,attr"`
+	Header   *ResponseHeader `xml:"env:Header,omitempty"`
+	Body     ResponseBody    `xml:"env:Body"`
+}
+
+/*
+Заголовок ответа: каждый блок сериализуется по своему XMLName
+*/
+type ResponseHeader struct {
+	Blocks []any
+}
 
+/*
+Блок env:NotUnderstood ответа с Fault MustUnderstand (SOAP 1.2)
+*/
+type NotUnderstood struct {
+	XMLName xml.Name `xml:"env:NotUnderstood"`
+	QName   string   `xml:"qname,attr"`
+	Xmlns   string   `xml:"xmlns:h,attr,omitempty"`
 }
 
+func NewNotUnderstood(name xml.Name) NotUnderstood {
+	if name.Space == "" {
+		return NotUnderstood{QName: name.Local}
+	}
+	return NotUnderstood{QName: "h:" + name.Local, Xmlns: name.Space}
+}
+
+type ResponseBody struct {
+	Content any
+}
+
+/*
+Тело запроса: дочерние элементы Body сохраняются целиком и декодируются
+в тип запроса операции после ее определения по имени элемента
+*/
+type Body struct {
+	Elements []Element
+}
+
+// Элемент XML, сохраненный в виде потока токенов
+type Element struct {
+	Name   xml.Name
+	Attr   []xml.Attr
+	tokens []xml.Token
+}
+
+func (b *Body) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	elements, err := readElements(d)
+	if err != nil {
+		return err
+	}
+	b.Elements = elements
+	return nil
+}
+
+// Читает дочерние элементы до закрывающего тега родителя
+func readElements(d *xml.Decoder) ([]Element, error) {
+	var elements []Element
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			element, err := readElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		case xml.EndElement:
+			return elements, nil
+		}
+	}
+}
 
+func readElement(d *xml.Decoder, start xml.StartElement) (Element, error) {
+	element := Element{Name: start.Name, Attr: start.Attr, tokens: []xml.Token{start.Copy()}}
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return Element{}, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		element.tokens = append(element.tokens, xml.CopyToken(token))
+	}
+	return element, nil
+}
+
+// Значение атрибута элемента по пространству имен и имени
+func (e Element) AttrValue(space string, local string) (string, bool) {
+	for _, attr := range e.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
 
+// Отмечен ли блок заголовка атрибутом mustUnderstand версии SOAP
+func (e Element) MustUnderstand(version SOAPVersion) bool {
+	value, _ := e.AttrValue(version.Namespace(), "mustUnderstand")
+	switch strings.TrimSpace(value) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+/*
+Адресован ли блок заголовка сервису: без роли (actor), с ролью next
+или ultimateReceiver (SOAP 1.2), с actor next (SOAP 1.1)
+*/
+func (e Element) TargetsReceiver(version SOAPVersion) bool {
+	if version == SOAP11 {
+		actor, ok := e.AttrValue(version.Namespace(), "actor")
+		return !ok || actor == SOAP11ActorNext
+	}
+	role, ok := e.AttrValue(version.Namespace(), "role")
+	return !ok || role == SOAP12RoleNext || role == SOAP12RoleUltimateReceiver
+}
 
+// Декодирует сохраненный элемент в структуру v
+func (e Element) Decode(v any) error {
+	return xml.NewTokenDecoder(&tokenReader{tokens: e.tokens}).Decode(v)
+}
+
+type tokenReader struct {
+	tokens []xml.Token
+}
+
+func (r *tokenReader) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return token, nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/xml"
+	"reflect"
+)
+
+// Пространство имен XML Schema instance (атрибут xsi:nil)
+const XMLSchemaInstanceNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+/*
+Поле запроса, различающее отсутствующий элемент и пустое значение:
+Present - элемент передан, Nil - элемент передан с xsi:nil="true" (Value - нулевое значение)
+*/
+type Optional[T any] struct {
+	Present bool
+	Nil     bool
+	Value   T
+}
+
+func (o *Optional[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*o = Optional[T]{Present: true}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == XMLSchemaInstanceNamespace && attr.Name.Local == "nil" {
+			o.Nil = attr.Value == "true" || attr.Value == "1"
+		}
+	}
+	if o.Nil {
+		return d.Skip()
+	}
+	return d.DecodeElement(&o.Value, &start)
+}
+
+// Значение, заданное элементом (не xsi:nil)
+func (o Optional[T]) Set() bool {
+	return o.Present && !o.Nil
+}
+
+// Тип значения для описания поля в XML Schema
+func (Optional[T]) ValueType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
@@ -1,10 +1,22 @@
 package models
 
+import "time"
+
+/*
+Запись Person. Version - версия записи для оптимистической блокировки:
+UpdatePerson принимает версию, полученную из GetPerson, и увеличивает ее.
+DeletedAt и DeletedBy заполнены у удаленной записи до ее окончательной очистки.
+Схема задается миграциями: уникальность Email обеспечивает частичный индекс
+idx_people_email (WHERE deleted_at IS NULL, миграция 0007), а не тег gorm
+*/
 type Person struct {
-	ID        uint   `gorm:"primaryKey; not null" xml:"id,omitempty" yaml:"id,omitempty"`
-	Name      string `gorm:"type:varchar(200)" xml:"name" yaml:"name"`
-	Surname   string `gorm:"type:varchar(200)" xml:"surname" yaml:"surname"`
-	Age       int    `gorm:"age,omitempty" xml:"age" yaml:"age"`
-	Email     string `gorm:"type:varchar(200); uniqueIndex; not null" xml:"email" yaml:"email"`
-	Telephone string `gorm:"type:varchar(200); not null" xml:"telephone" yaml:"telephone"`
+	ID        uint       `gorm:"primaryKey; not null" xml:"id,omitempty" yaml:"id,omitempty"`
+	Name      string     `gorm:"type:varchar(200)" xml:"name" yaml:"name"`
+	Surname   string     `gorm:"type:varchar(200)" xml:"surname" yaml:"surname"`
+	Age       int        `gorm:"age,omitempty" xml:"age" yaml:"age"`
+	Email     string     `gorm:"type:varchar(200); not null" xml:"email" yaml:"email"`
+	Telephone string     `gorm:"type:varchar(200); not null" xml:"telephone" yaml:"telephone"`
+	Version   uint       `gorm:"not null; default:1" xml:"version,omitempty" yaml:"-"`
+	DeletedAt *time.Time `xml:"-" yaml:"-"`
+	DeletedBy string     `gorm:"type:varchar(100); not null; default:''" xml:"-" yaml:"-"`
 }
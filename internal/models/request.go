@@ -12,30 +12,154 @@ type DeletePersonRequest struct {
 	ID int `xml:"ID"`
 }
 
+// Режимы обновления: замена всех полей (по умолчанию) или только переданных
+const (
+	UpdateModeReplace = "replace"
+	UpdateModePatch   = "patch"
+)
 
+/*
+Запрос обновления; Version - версия записи из GetPerson (обязательна).
+В режиме patch изменяются только переданные поля, xsi:nil="true" очищает поле
+*/
 type UpdatePersonRequest struct {
-	ID        uint    `xml:"ID"`
-	Name      string `xml:"Name"`
-	Surname   string `xml:"Surname"`
-	Age       int    `xml:"Age"`
-	Email     string `xml:"Email"`
-	Telephone string `xml:"Telephone"`
+	ID        uint             `xml:"ID"`
+	Version   uint             `xml:"Version"`
+	Mode      string           `xml:"Mode,omitempty"`
+	Name      Optional[string] `xml:"Name"`
+	Surname   Optional[string] `xml:"Surname"`
+	Age       Optional[int]    `xml:"Age"`
+	Email     Optional[string] `xml:"Email"`
+	Telephone Optional[string] `xml:"Telephone"`
 }
 
-type GetPersonRequest struct {
+/*
+Восстановление записи, удаленной DeletePerson
+*/
+type RestorePersonRequest struct {
+	ID uint `xml:"ID"`
+}
+
+/*
+Окончательное удаление записи, ранее удаленной DeletePerson
+*/
+type PurgePersonRequest struct {
 	ID uint `xml:"ID"`
 }
 
-type GetAllPersonsRequest struct{}
+/*
+Журнал изменений записи
+*/
+type GetPersonHistoryRequest struct {
+	ID uint `xml:"ID"`
+}
+
+/*
+Получение записи; AsOf - номер ревизии или время (RFC 3339) для чтения прежнего состояния
+(требует разрешения persons:audit, как журнал изменений)
+*/
+type GetPersonRequest struct {
+	ID   uint   `xml:"ID"`
+	AsOf string `xml:"AsOf,omitempty"`
+}
+
+/*
+Возврат записи к ревизии Revision; Version - текущая версия записи
+*/
+type RevertPersonRequest struct {
+	ID       uint `xml:"ID"`
+	Revision uint `xml:"Revision"`
+	Version  uint `xml:"Version"`
+}
+
+/*
+Параметры постраничного вывода: размер страницы, токен продолжения
+из NextPageToken предыдущего ответа и сортировка (поле, asc/desc)
+*/
+type Paging struct {
+	PageSize  int    `xml:"PageSize,omitempty"`
+	PageToken string `xml:"PageToken,omitempty"`
+	SortBy    string `xml:"SortBy,omitempty"`
+	SortOrder string `xml:"SortOrder,omitempty"`
+}
 
+type GetAllPersonsRequest struct {
+	Paging
+}
+
+/*
+Условие по строковому полю: Op - equals, prefix или contains (по умолчанию),
+IgnoreCase - сравнение без учета регистра
+*/
+type StringFilter struct {
+	Value      string `xml:"Value"`
+	Op         string `xml:"Op,omitempty"`
+	IgnoreCase bool   `xml:"IgnoreCase,omitempty"`
+}
+
+/*
+Диапазон возраста, границы включительно
+*/
+type AgeRange struct {
+	Min *int `xml:"Min,omitempty"`
+	Max *int `xml:"Max,omitempty"`
+}
+
+/*
+Критерии поиска по полям. Match: all - все условия (AND, по умолчанию), any - любое (OR)
+*/
+type SearchCriteria struct {
+	Match     string        `xml:"Match,omitempty"`
+	Name      *StringFilter `xml:"Name,omitempty"`
+	Surname   *StringFilter `xml:"Surname,omitempty"`
+	Email     *StringFilter `xml:"Email,omitempty"`
+	Telephone *StringFilter `xml:"Telephone,omitempty"`
+	Age       *AgeRange     `xml:"Age,omitempty"`
+}
+
+// Режимы поиска: точный (по умолчанию) и ранжированный по релевантности
+const (
+	SearchModeExact  = "exact"
+	SearchModeRanked = "ranked"
+)
+
+/*
+Запрос поиска: свободная строка Query либо структурированные критерии Criteria.
+В режиме ranked используется только Query, результаты упорядочены по релевантности
+*/
 type SearchPersonRequest struct {
-	Query string `xml:"Query"`
-}
-type Body struct {
-	AddPerson      *AddPersonRequest      `xml:"AddPerson,omitempty"`
-	DeletePerson   *DeletePersonRequest   `xml:"DeletePerson,omitempty"`
-	UpdatePerson   *UpdatePersonRequest   `xml:"UpdatePerson,omitempty"`
-	GetPerson      *GetPersonRequest      `xml:"GetPerson,omitempty"`
-	GetAllPersons  *GetAllPersonsRequest  `xml:"GetAllPersons,omitempty"`
-	SearchPerson   *SearchPersonRequest   `xml:"SearchPerson,omitempty"`
-}
\ No newline at end of file
+	Mode     string          `xml:"Mode,omitempty"`
+	Query    string          `xml:"Query,omitempty"`
+	Criteria *SearchCriteria `xml:"Criteria,omitempty"`
+	Paging
+}
+
+// Режимы пакетной операции: все или ничего (по умолчанию) и фиксация успешных элементов
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "bestEffort"
+)
+
+/*
+Пакетное добавление: элементы Person выполняются в одной транзакции
+*/
+type AddPersonsRequest struct {
+	Mode    string             `xml:"Mode,omitempty"`
+	Persons []AddPersonRequest `xml:"Person"`
+}
+
+/*
+Пакетное обновление: элементы Person выполняются в одной транзакции
+*/
+type UpdatePersonsRequest struct {
+	Mode    string                `xml:"Mode,omitempty"`
+	Persons []UpdatePersonRequest `xml:"Person"`
+}
+
+/*
+Пакетное удаление по списку ID в одной транзакции
+*/
+type DeletePersonsRequest struct {
+	Mode string `xml:"Mode,omitempty"`
+	IDs  []uint `xml:"ID"`
+}
@@ -1,13 +1,25 @@
 package models
 
+import "encoding/xml"
+
+/*
+Сведения о странице: общее количество записей и токен следующей страницы
+*/
+type PageInfo struct {
+	TotalCount    int64  `xml:"TotalCount"`
+	NextPageToken string `xml:"NextPageToken,omitempty"`
+}
+
 type GetAllPersonsResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons GetAllPersonsResponse"`
 	Persons []Person `xml:"persons"`
+	PageInfo
 }
 type GetPersonResponse struct {
-    Person Person `xml:"Person"` 
+	XMLName xml.Name `xml:"http://wst.lab/persons GetPersonResponse"`
+	Person  Person   `xml:"Person"`
 }
 
-
 type ErrorResponse struct {
 	Type     string `xml:"type"`
 	Title    string `xml:"title"`
@@ -16,19 +28,100 @@ type ErrorResponse struct {
 	Instance string `xml:"instance"`
 }
 
-type DeleteResponse struct {
-	Status bool `xml:"status"`
+type DeletePersonResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons DeletePersonResponse"`
+	Status  bool     `xml:"status"`
 }
 
-type SearchPersonResponse struct {
-    Persons []Person `xml:"Persons"`
+/*
+Запись результата поиска; Score - релевантность в режиме ranked
+*/
+type ScoredPerson struct {
+	Person
+	Score float64 `xml:"score,omitempty"`
 }
 
+type SearchPersonResponse struct {
+	XMLName xml.Name       `xml:"http://wst.lab/persons SearchPersonResponse"`
+	Persons []ScoredPerson `xml:"Persons"`
+	PageInfo
+}
 
 type AddPersonResponse struct {
-    ID uint `xml:"ID"`
+	XMLName xml.Name `xml:"http://wst.lab/persons AddPersonResponse"`
+	ID      uint     `xml:"ID"`
+}
+
+type RestorePersonResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons RestorePersonResponse"`
+	Person  Person   `xml:"Person"`
+}
+
+type PurgePersonResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons PurgePersonResponse"`
+	Status  bool     `xml:"status"`
+}
+
+type RevertPersonResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons RevertPersonResponse"`
+	Person  Person   `xml:"Person"`
+}
+
+type GetPersonHistoryResponse struct {
+	XMLName xml.Name     `xml:"http://wst.lab/persons GetPersonHistoryResponse"`
+	Entries []AuditEntry `xml:"Entry"`
 }
 
 type UpdatePersonResponse struct {
-    Status bool `xml:"status"`
-}
\ No newline at end of file
+	XMLName xml.Name `xml:"http://wst.lab/persons UpdatePersonResponse"`
+	Status  bool     `xml:"status"`
+	Version uint     `xml:"version"`
+}
+
+// Состояния элемента пакета
+const (
+	BatchStatusOK         = "ok"
+	BatchStatusFailed     = "failed"
+	BatchStatusRolledBack = "rolledBack"
+)
+
+/*
+Результат элемента пакета (Index - номер элемента в запросе с нуля).
+Status ok - изменение зафиксировано, failed - ошибка элемента (errorCode, subcode, errorMessage
+как в Fault), rolledBack - элемент выполнен, но пакет atomic отменен из-за ошибок других элементов
+*/
+type BatchItemResult struct {
+	Index        int         `xml:"Index"`
+	Status       string      `xml:"Status"`
+	ID           uint        `xml:"ID,omitempty"`
+	ErrorCode    string      `xml:"errorCode,omitempty"`
+	Subcode      string      `xml:"subcode,omitempty"`
+	ErrorMessage string      `xml:"errorMessage,omitempty"`
+	Violations   []Violation `xml:"Violation,omitempty"`
+}
+
+/*
+Итог пакетной операции: зафиксирована ли транзакция, количество успешных
+и ошибочных элементов и результаты по каждому элементу
+*/
+type BatchSummary struct {
+	Committed bool              `xml:"Committed"`
+	Succeeded int               `xml:"Succeeded"`
+	Failed    int               `xml:"Failed"`
+	Results   []BatchItemResult `xml:"Result"`
+}
+
+type AddPersonsResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons AddPersonsResponse"`
+	BatchSummary
+}
+
+type UpdatePersonsResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons UpdatePersonsResponse"`
+	BatchSummary
+}
+
+type DeletePersonsResponse struct {
+	XMLName xml.Name `xml:"http://wst.lab/persons DeletePersonsResponse"`
+	BatchSummary
+}
@@ -0,0 +1,36 @@
+package models
+
+// Пространства имен WS-Security 1.0
+const (
+	WSSecurityNamespace = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	WSUtilityNamespace  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+)
+
+// Типы пароля UsernameToken Profile 1.0
+const (
+	PasswordTypeText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	PasswordTypeDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+)
+
+/*
+Заголовок wsse:Security
+*/
+type Security struct {
+	UsernameToken *UsernameToken `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd UsernameToken"`
+}
+
+/*
+wsse:UsernameToken: пароль открытым текстом (PasswordText) или
+Base64(SHA-1(Nonce + Created + пароль)) (PasswordDigest)
+*/
+type UsernameToken struct {
+	Username string           `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Username"`
+	Password SecurityPassword `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Password"`
+	Nonce    string           `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Nonce"`
+	Created  string           `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+}
+
+type SecurityPassword struct {
+	Type  string `xml:"Type,attr"`
+	Value string `xml:",chardata"`
+}
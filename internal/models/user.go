@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Роли пользователей
+const (
+	RoleReader = "reader" // чтение и поиск записей
+	RoleEditor = "editor" // чтение, добавление и изменение записей
+	RoleAdmin  = "admin"  // все операции, включая удаление
+)
+
+// Существует ли роль
+func ValidRole(role string) bool {
+	switch role {
+	case RoleReader, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+/*
+Пользователь сервиса с ролью. Пароль хранится только в виде bcrypt хеша.
+PasswordDigest требует исходного секрета, поэтому для него задается
+отдельный секрет DigestSecret; пустой секрет запрещает PasswordDigest.
+DigestSecret хранится зашифрованным ключом сервера (AES-GCM, префикс "v1:",
+ключ в WST_DIGEST_KEY); значение без префикса - устаревший открытый секрет, он не принимается
+*/
+type User struct {
+	ID           uint      `gorm:"primaryKey; not null" yaml:"-"`
+	Username     string    `gorm:"type:varchar(100); uniqueIndex; not null" yaml:"username"`
+	PasswordHash string    `gorm:"type:varchar(200); not null" yaml:"passwordHash"`
+	Role         string    `gorm:"type:varchar(20); not null" yaml:"role"`          // reader, editor, admin
+	DigestSecret string    `gorm:"type:varchar(200); not null" yaml:"digestSecret"` // для WS-Security PasswordDigest
+	Disabled     bool      `gorm:"not null; default:false" yaml:"disabled"`
+	CreatedAt    time.Time `yaml:"-"`
+	UpdatedAt    time.Time `yaml:"-"`
+}
@@ -1,25 +1,55 @@
 package transport
 
 import (
-	"WST_lab1_server_new1/internal/database/postgres"
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/auth"
+	"WST_lab1_server_new1/internal/database"
 	"WST_lab1_server_new1/internal/handlers"
+	"WST_lab1_server_new1/internal/logging"
 	"WST_lab1_server_new1/internal/middleware"
+	"WST_lab1_server_new1/internal/validation"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-func Init(httpserver *gin.Engine, storage *postgres.Storage) {
+func Init(httpserver *gin.Engine, storage *database.Storage) {
 	//middleware для обработки ошибок
 	httpserver.Use(middleware.ErrorHandler())
 
 	//Восстановление после паники
 	httpserver.Use(gin.Recovery())
 	//Логгирование
-	httpserver.Use(gin.Logger())
-	//debug печать полного запроса
-	httpserver.Use(middleware.PrintFullReques())
+	httpserver.Use(middleware.RequestLogger())
 
-	handler := &handlers.StorageHandler{Storage: storage}
+	//Правила проверки полей Person из конфигурации
+	rules, err := validation.New(*config.ValidationSetting)
+	if err != nil {
+		logging.Logger.Fatal("Invalid validation rules", zap.Error(err))
+	}
+
+	//Ключ шифрования секретов PasswordDigest; без ключа доступен только PasswordText и HTTP Basic
+	digestKey, err := auth.DigestKeyFromEnv()
+	if err != nil {
+		logging.Logger.Fatal("Invalid "+auth.DigestKeyEnv, zap.Error(err))
+	}
+	if digestKey == nil {
+		logging.Logger.Warn(auth.DigestKeyEnv + " is not set: WS-Security PasswordDigest is disabled")
+	}
+
+	handler := &handlers.StorageHandler{
+		Storage: storage,
+		//Проверка WS-Security UsernameToken с защитой от повторов
+		Tokens: &auth.TokenAuthenticator{
+			Users:     storage.UserRepository,
+			Nonces:    auth.NewNonceCache(),
+			MaxAge:    config.GeneralServerSetting.TokenMaxAge,
+			DigestKey: digestKey,
+		},
+		Rules: rules,
+	}
 	//Подключение к БД
 	httpserver.POST("/soap", handler.SOAPHandler)
+	//WSDL и XSD описание сервиса
+	httpserver.GET("/soap", handler.WSDLHandler)
 }
@@ -0,0 +1,230 @@
+package validation
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/models"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Правила, указываемые в нарушениях
+const (
+	RuleRequired  = "required"
+	RuleMinLength = "minLength"
+	RuleMaxLength = "maxLength"
+	RulePattern   = "pattern"
+	RuleMin       = "min"
+	RuleMax       = "max"
+	RuleFormat    = "format"
+	RuleDomain    = "domain"
+)
+
+// Поля Person в порядке проверки
+var Fields = []string{"name", "surname", "age", "email", "telephone"}
+
+// Размер строковых столбцов таблицы people
+const ColumnLength = 200
+
+// Формат телефона, если форматы не заданы в конфигурации
+const DefaultPhonePattern = `^\+7\d{10}$`
+
+/*
+Ошибка проверки: все нарушения правил по переданным полям
+*/
+type Error struct {
+	Violations []models.Violation
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		parts[i] = violation.Field + ": " + violation.Rule
+	}
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// Правила одного поля
+type fieldRules struct {
+	required       bool
+	minLength      int
+	maxLength      int
+	patterns       []*regexp.Regexp
+	min            *int
+	max            *int
+	allowedDomains []string
+	deniedDomains  []string
+}
+
+/*
+Набор правил проверки полей Person, построенный по конфигурации
+*/
+type Rules struct {
+	fields map[string]*fieldRules
+}
+
+/*
+Функция построения правил по конфигурации. Email обязателен всегда, телефон
+без заданных форматов проверяется по DefaultPhonePattern
+*/
+func New(cfg config.ValidationConfig) (*Rules, error) {
+	rules := &Rules{fields: map[string]*fieldRules{}}
+	configs := map[string]config.FieldRulesConfig{
+		"name":      cfg.Name,
+		"surname":   cfg.Surname,
+		"age":       cfg.Age,
+		"email":     cfg.Email,
+		"telephone": cfg.Telephone,
+	}
+	for _, field := range Fields {
+		fc := configs[field]
+		fr := &fieldRules{
+			required:       fc.Required || field == "email",
+			minLength:      fc.MinLength,
+			maxLength:      fc.MaxLength,
+			min:            fc.Min,
+			max:            fc.Max,
+			allowedDomains: normalizeDomains(fc.AllowedDomains),
+			deniedDomains:  normalizeDomains(fc.DeniedDomains),
+		}
+		if fr.maxLength == 0 || fr.maxLength > ColumnLength {
+			fr.maxLength = ColumnLength
+		}
+		if fr.minLength < 0 || fr.minLength > fr.maxLength {
+			return nil, fmt.Errorf("validation rules for %s: minLength must be between 0 and %d", field, fr.maxLength)
+		}
+		if fr.min != nil && fr.max != nil && *fr.min > *fr.max {
+			return nil, fmt.Errorf("validation rules for %s: min greater than max", field)
+		}
+		patterns := fc.Patterns
+		if field == "telephone" && len(patterns) == 0 {
+			patterns = []string{DefaultPhonePattern}
+		}
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("validation rules for %s: %w", field, err)
+			}
+			fr.patterns = append(fr.patterns, re)
+		}
+		rules.fields[field] = fr
+	}
+	return rules, nil
+}
+
+// Домены в нижнем регистре без ведущих точек и @
+func normalizeDomains(domains []string) []string {
+	var result []string
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), ".@")); domain != "" {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+// Совпадение домена или его поддомена с одним из доменов списка
+func matchDomain(domain string, domains []string) bool {
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Метод проверки полей записи: fields - проверяемые поля (по умолчанию все).
+Возвращает все нарушения; nil - запись корректна
+*/
+func (r *Rules) Person(person *models.Person, fields ...string) []models.Violation {
+	if len(fields) == 0 {
+		fields = Fields
+	}
+	var violations []models.Violation
+	add := func(field string, rule string, message string) {
+		violations = append(violations, models.Violation{Field: field, Rule: rule, Message: message})
+	}
+	for _, field := range fields {
+		fr, ok := r.fields[field]
+		if !ok {
+			continue
+		}
+		if field == "age" {
+			if fr.min != nil && person.Age < *fr.min {
+				add(field, RuleMin, "Значение должно быть не меньше "+strconv.Itoa(*fr.min))
+			}
+			if fr.max != nil && person.Age > *fr.max {
+				add(field, RuleMax, "Значение должно быть не больше "+strconv.Itoa(*fr.max))
+			}
+			continue
+		}
+		value := stringField(person, field)
+		if value == "" {
+			//Пустое необязательное поле остальными правилами не проверяется
+			if fr.required {
+				add(field, RuleRequired, "Поле обязательно")
+			}
+			continue
+		}
+		length := utf8.RuneCountInString(value)
+		if length < fr.minLength {
+			add(field, RuleMinLength, "Длина должна быть не меньше "+strconv.Itoa(fr.minLength)+" символов")
+		}
+		if length > fr.maxLength {
+			add(field, RuleMaxLength, "Длина должна быть не больше "+strconv.Itoa(fr.maxLength)+" символов")
+		}
+		if len(fr.patterns) > 0 && !matchAny(fr.patterns, value) {
+			add(field, RulePattern, "Значение не соответствует допустимому формату")
+		}
+		if field == "email" {
+			address, err := mail.ParseAddress(value)
+			if err != nil {
+				add(field, RuleFormat, "Некорректный email")
+				continue
+			}
+			domain := strings.ToLower(address.Address[strings.LastIndex(address.Address, "@")+1:])
+			if (len(fr.allowedDomains) > 0 && !matchDomain(domain, fr.allowedDomains)) || matchDomain(domain, fr.deniedDomains) {
+				add(field, RuleDomain, "Домен email не допускается")
+			}
+		}
+	}
+	return violations
+}
+
+/*
+Метод проверки записи, возвращающий *Error со всеми нарушениями
+*/
+func (r *Rules) Check(person *models.Person, fields ...string) error {
+	if violations := r.Person(person, fields...); len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+func matchAny(patterns []*regexp.Regexp, value string) bool {
+	for _, re := range patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Значение строкового поля записи
+func stringField(person *models.Person, field string) string {
+	switch field {
+	case "name":
+		return person.Name
+	case "surname":
+		return person.Surname
+	case "email":
+		return person.Email
+	case "telephone":
+		return person.Telephone
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"WST_lab1_server_new1/config"
+	"WST_lab1_server_new1/internal/models"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Нарушения в виде пар поле:правило
+func rulesOf(violations []models.Violation) []string {
+	var result []string
+	for _, violation := range violations {
+		result = append(result, violation.Field+":"+violation.Rule)
+	}
+	return result
+}
+
+func validPerson() *models.Person {
+	return &models.Person{Name: "Иван", Surname: "Иванов", Age: 30, Email: "ivan@mail.ru", Telephone: "+79001234567"}
+}
+
+func TestDefaultRules(t *testing.T) {
+	rules, err := New(config.ValidationConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := rules.Person(validPerson()); violations != nil {
+		t.Errorf("valid person: %v", violations)
+	}
+	got := rulesOf(rules.Person(&models.Person{Email: "", Telephone: "123"}))
+	want := []string{"email:required", "telephone:pattern"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+}
+
+func TestConfiguredRules(t *testing.T) {
+	minAge, maxAge := 18, 99
+	rules, err := New(config.ValidationConfig{
+		Name:      config.FieldRulesConfig{Required: true, MinLength: 2, MaxLength: 10},
+		Age:       config.FieldRulesConfig{Min: &minAge, Max: &maxAge},
+		Email:     config.FieldRulesConfig{AllowedDomains: []string{"mail.ru"}, DeniedDomains: []string{"spam.mail.ru"}},
+		Telephone: config.FieldRulesConfig{Patterns: []string{`^\+7\d{10}$`, `^8\d{10}$`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	person := validPerson()
+	person.Telephone = "89001234567"
+	person.Email = "ivan@inbox.mail.ru"
+	if violations := rules.Person(person); violations != nil {
+		t.Errorf("valid person: %v", violations)
+	}
+
+	person = &models.Person{Name: "И", Age: 150, Email: "ivan@spam.mail.ru", Telephone: "+79001234567"}
+	got := rulesOf(rules.Person(person))
+	want := []string{"name:minLength", "age:max", "email:domain"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+
+	//Проверяются только переданные поля
+	err = rules.Check(person, "age")
+	var invalid *Error
+	if !errors.As(err, &invalid) || !reflect.DeepEqual(rulesOf(invalid.Violations), []string{"age:max"}) {
+		t.Errorf("Check(age) = %v", err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	minAge, maxAge := 50, 10
+	configs := []config.ValidationConfig{
+		{Name: config.FieldRulesConfig{MinLength: 20, MaxLength: 10}},
+		{Age: config.FieldRulesConfig{Min: &minAge, Max: &maxAge}},
+		{Telephone: config.FieldRulesConfig{Patterns: []string{"("}}},
+	}
+	for i, cfg := range configs {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("config %d: expected error", i)
+		}
+	}
+}